@@ -1,10 +1,9 @@
 package configcat
 
 import (
-	"errors"
+	"context"
 	"sync"
 	"sync/atomic"
-	"time"
 )
 
 // async describes an object which used to control asynchronous operations.
@@ -47,16 +46,15 @@ func (async *async) isPending() bool {
 //     fmt.Print("operation completed")
 //  })
 func (async *async) accept(completion func()) *async {
-	if async.isCompleted() {
-		completion()
-	}
-
+	async.Lock()
 	if async.isPending() {
-		async.Lock()
 		async.completions = append(async.completions, completion)
 		async.Unlock()
+		return async
 	}
+	async.Unlock()
 
+	completion()
 	return async
 }
 
@@ -78,16 +76,22 @@ func (async *async) apply(completion func() interface{}) *asyncResult {
 }
 
 // complete moves the async operation into the completed state.
+// The waiters are released only after the chained completions ran,
+// so they can rely on the effects of those completions.
 func (async *async) complete() {
-	if atomic.CompareAndSwapUint32(&async.state, pending, completed) {
-		close(async.done)
-		async.RLock()
-		defer async.RUnlock()
-		for _, comp := range async.completions {
-			comp()
-		}
+	async.Lock()
+	if !atomic.CompareAndSwapUint32(&async.state, pending, completed) {
+		async.Unlock()
+		return
 	}
+	completions := async.completions
 	async.completions = nil
+	async.Unlock()
+
+	for _, comp := range completions {
+		comp()
+	}
+	close(async.done)
 }
 
 // wait blocks until the async operation is completed.
@@ -95,14 +99,12 @@ func (async *async) wait() {
 	<-async.done
 }
 
-// waitOrTimeout blocks until the async operation is completed or until
-// the given timeout duration expires.
-func (async *async) waitOrTimeout(duration time.Duration) error {
-	timer := time.NewTimer(duration)
-	defer timer.Stop()
+// waitCtx blocks until the async operation is completed or until
+// the given context is done, in which case the error of the context is returned.
+func (async *async) waitCtx(ctx context.Context) error {
 	select {
-	case <-timer.C:
-		return errors.New("operation cancelled")
+	case <-ctx.Done():
+		return ctx.Err()
 	case <-async.done:
 		return nil
 	}
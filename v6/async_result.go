@@ -1,8 +1,7 @@
 package configcat
 
 import (
-	"errors"
-	"time"
+	"context"
 )
 
 // AsyncResult describes an object which used to control asynchronous operations with return value.
@@ -99,15 +98,12 @@ func (asyncResult *asyncResult) get() interface{} {
 	return asyncResult.result
 }
 
-// GetOrTimeout blocks until the async operation is completed or until
-// the given timeout duration expires, then returns the result of the operation.
-func (asyncResult *asyncResult) getOrTimeout(duration time.Duration) (interface{}, error) {
-	timer := time.NewTimer(duration)
-	defer timer.Stop()
-
+// getCtx blocks until the async operation is completed or until the given
+// context is done, then returns the result of the operation or the error of the context.
+func (asyncResult *asyncResult) getCtx(ctx context.Context) (interface{}, error) {
 	select {
-	case <-timer.C:
-		return nil, errors.New("operation cancelled")
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	case <-asyncResult.done:
 		return asyncResult.result, nil
 	}
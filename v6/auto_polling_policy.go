@@ -1,19 +1,24 @@
 package configcat
 
 import (
+	"context"
+	"math"
 	"sync/atomic"
 	"time"
 )
 
 // autoPollingPolicy describes a refreshPolicy which polls the latest configuration over HTTP and updates the local cache repeatedly.
 type autoPollingPolicy struct {
-	configRefresher
-	autoPollInterval time.Duration
-	init             *async
-	initialized      uint32
-	stop             chan struct{}
-	closed           uint32
-	configChanged    func()
+	*configRefresher
+	autoPollInterval   time.Duration
+	jitter             float64
+	randomizeFirstPoll bool
+	init               *async
+	initialized        uint32
+	stop               chan struct{}
+	resume             chan struct{}
+	closed             uint32
+	configChanged      func()
 }
 
 // autoPollConfig describes the configuration for auto polling.
@@ -22,6 +27,10 @@ type autoPollConfig struct {
 	autoPollInterval time.Duration
 	// The configuration change listener.
 	changeListener func()
+	// The fraction of the interval by which the polls are randomized.
+	jitter float64
+	// Whether the first poll is delayed randomly when the cache holds a configuration.
+	randomizeFirstPoll bool
 }
 
 func (config autoPollConfig) getModeIdentifier() string {
@@ -44,20 +53,31 @@ func AutoPollWithChangeListener(
 	return autoPollConfig{autoPollInterval: interval, changeListener: changeListener}
 }
 
+// AutoPollWithJitter creates an auto polling refresh mode which randomizes the polls, so the
+// instances of a service started at the same time don't poll in lockstep. Each poll is delayed by
+// the interval randomized by the jitter fraction of it, so for example with a 60s interval and
+// 0.1 jitter, the polls are 54s to 66s apart. The jitter is limited to the [0, 1] range, so the
+// polls are at most twice the interval apart. When randomizeFirstPoll is set and the cache
+// already holds a configuration, e.g. a shared cache, the first poll is delayed randomly
+// within the interval. Otherwise the first poll is made right away.
+func AutoPollWithJitter(interval time.Duration, jitter float64, randomizeFirstPoll bool) RefreshMode {
+	return autoPollConfig{autoPollInterval: interval, jitter: jitter, randomizeFirstPoll: randomizeFirstPoll}
+}
+
 // newAutoPollingPolicy initializes a new autoPollingPolicy.
 func newAutoPollingPolicy(
-	configFetcher configProvider,
-	cache ConfigCache,
-	logger Logger,
-	sdkKey string,
+	refresher *configRefresher,
 	autoPollConfig autoPollConfig) *autoPollingPolicy {
 	policy := &autoPollingPolicy{
-		configRefresher:  newConfigRefresher(configFetcher, cache, logger, sdkKey),
-		autoPollInterval: autoPollConfig.autoPollInterval,
-		init:             newAsync(),
-		initialized:      no,
-		stop:             make(chan struct{}),
-		configChanged:    autoPollConfig.changeListener,
+		configRefresher:    refresher,
+		autoPollInterval:   autoPollConfig.autoPollInterval,
+		jitter:             math.Max(0, math.Min(1, autoPollConfig.jitter)),
+		randomizeFirstPoll: autoPollConfig.randomizeFirstPoll,
+		init:               newAsync(),
+		initialized:        no,
+		stop:               make(chan struct{}),
+		resume:             make(chan struct{}, 1),
+		configChanged:      autoPollConfig.changeListener,
 	}
 	policy.startPolling()
 	return policy
@@ -70,10 +90,22 @@ func (policy *autoPollingPolicy) getConfigurationAsync() *asyncResult {
 	}
 
 	return policy.init.apply(func() interface{} {
-		return policy.get()
+		return policy.get(context.Background())
 	})
 }
 
+// setOffline switches the offline mode. The polling is paused while
+// offline, and a poll is made right away when going online.
+func (policy *autoPollingPolicy) setOffline(offline bool) {
+	policy.configRefresher.setOffline(offline)
+	if !offline {
+		select {
+		case policy.resume <- struct{}{}:
+		default:
+		}
+	}
+}
+
 // close shuts down the policy.
 func (policy *autoPollingPolicy) close() {
 	if atomic.CompareAndSwapUint32(&policy.closed, no, yes) {
@@ -84,34 +116,83 @@ func (policy *autoPollingPolicy) close() {
 func (policy *autoPollingPolicy) startPolling() {
 	policy.logger.Debugf("Auto polling started with %+v interval.", policy.autoPollInterval)
 
-	ticker := time.NewTicker(policy.autoPollInterval)
-
 	go func() {
-		defer ticker.Stop()
-		policy.poll()
+		if !policy.initialPoll() {
+			return
+		}
+		next := policy.clock.After(policy.nextPollDelay())
 		for {
 			select {
 			case <-policy.stop:
 				policy.logger.Debugf("Auto polling stopped.")
 				return
-			case <-ticker.C:
+			case <-next:
+				policy.poll()
+				next = policy.clock.After(policy.nextPollDelay())
+			case <-policy.resume:
 				policy.poll()
 			}
 		}
 	}()
 }
 
+// initialPoll polls unless the cache, which might be shared with other
+// processes, holds a configuration fetched within the poll interval.
+// It returns false when the policy was closed in the meantime.
+func (policy *autoPollingPolicy) initialPoll() bool {
+	cached := policy.get(context.Background())
+	if cached != nil && policy.clock.Now().Sub(cached.fetchTime) < policy.autoPollInterval {
+		policy.logger.Debugln("The cached configuration is fresh, initial poll skipped.")
+		policy.completeInit()
+		return true
+	}
+
+	if cached != nil && policy.randomizeFirstPoll {
+		// The cached configuration is served until the delayed first poll.
+		policy.completeInit()
+		delay := time.Duration(policy.random.float64() * float64(policy.autoPollInterval))
+		policy.logger.Debugf("First poll delayed by %+v.", delay)
+		select {
+		case <-policy.stop:
+			return false
+		case <-policy.clock.After(delay):
+		}
+	}
+
+	policy.poll()
+	return true
+}
+
+// nextPollDelay returns the delay of the next poll.
+func (policy *autoPollingPolicy) nextPollDelay() time.Duration {
+	return policy.random.jitter(policy.autoPollInterval, policy.jitter)
+}
+
 func (policy *autoPollingPolicy) poll() {
+	if policy.isOffline() {
+		policy.logger.Debugln("Offline, polling skipped.")
+		policy.completeInit()
+		return
+	}
+
+	if policy.inBackoff() {
+		policy.logger.Debugln("Backing off after failed fetches, polling skipped.")
+		return
+	}
+
 	policy.logger.Debugln("Polling the latest configuration.")
-	response := policy.configFetcher.getConfigurationAsync().get().(fetchResponse)
-	cached := policy.get()
-	if response.isFetched() && cached != response.body {
-		policy.set(response.body)
-		if policy.configChanged != nil {
-			policy.configChanged()
-		}
+	ctx := context.Background()
+	response := policy.fetchAsync(ctx).get().(fetchResponse)
+	cached := policy.get(ctx)
+	if _, err := policy.update(ctx, response); err == nil && response.isFetched() &&
+		(cached == nil || cached.jsonBody != response.body) && policy.configChanged != nil {
+		policy.configChanged()
 	}
 
+	policy.completeInit()
+}
+
+func (policy *autoPollingPolicy) completeInit() {
 	if atomic.CompareAndSwapUint32(&policy.initialized, no, yes) {
 		policy.init.complete()
 	}
@@ -119,5 +200,5 @@ func (policy *autoPollingPolicy) poll() {
 
 func (policy *autoPollingPolicy) readCache() *asyncResult {
 	policy.logger.Debugln("Reading from cache.")
-	return asCompletedAsyncResult(policy.get())
+	return asCompletedAsyncResult(policy.get(context.Background()))
 }
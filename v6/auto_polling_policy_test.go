@@ -1,6 +1,8 @@
 package configcat
 
 import (
+	"context"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -8,35 +10,36 @@ import (
 func TestAutoPollingPolicy_GetConfigurationAsync(t *testing.T) {
 	fetcher := newFakeConfigProvider()
 
-	fetcher.SetResponse(fetchResponse{status: Fetched, body: "test"})
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test")})
 	logger := DefaultLogger(LogLevelWarn)
-	policy := newAutoPollingPolicy(
-		fetcher,
-		newInMemoryConfigCache(),
-		logger,
-		"",
-		autoPollConfig{time.Second * 2, nil},
-	)
+	clock := NewManualClock(time.Now())
+	refresher := newConfigRefresher(fetcher, NewInMemoryCache(), logger, "")
+	refresher.clock = clock
+	policy := newAutoPollingPolicy(refresher, autoPollConfig{autoPollInterval: time.Second * 2})
 	defer policy.close()
 
-	config := policy.getConfigurationAsync().get().(string)
+	config := configValue(policy.getConfigurationAsync().get())
 
 	if config != "test" {
 		t.Error("Expecting test as result")
 	}
 
-	fetcher.SetResponse(fetchResponse{status: Fetched, body: "test2"})
-	config = policy.getConfigurationAsync().get().(string)
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test2")})
+	clock.Advance(time.Second)
+	config = configValue(policy.getConfigurationAsync().get())
 
 	if config != "test" {
 		t.Error("Expecting test as result")
 	}
 
-	time.Sleep(time.Second * 4)
-	config = policy.getConfigurationAsync().get().(string)
-
-	if config != "test2" {
-		t.Error("Expecting test2 as result")
+	// The poll runs on the polling goroutine, so the clock is advanced until it has happened.
+	deadline := time.Now().Add(time.Second)
+	for configValue(policy.getConfigurationAsync().get()) != "test2" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expecting test2 as result")
+		}
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
 	}
 }
 
@@ -46,15 +49,12 @@ func TestAutoPollingPolicy_GetConfigurationAsync_Fail(t *testing.T) {
 	fetcher.SetResponse(fetchResponse{status: Failure, body: ""})
 	logger := DefaultLogger(LogLevelWarn)
 	policy := newAutoPollingPolicy(
-		fetcher,
-		newInMemoryConfigCache(),
-		logger,
-		"",
-		autoPollConfig{time.Second * 2, nil},
+		newConfigRefresher(fetcher, NewInMemoryCache(), logger, ""),
+		autoPollConfig{autoPollInterval: time.Second * 2},
 	)
 	defer policy.close()
 
-	config := policy.getConfigurationAsync().get().(string)
+	config := configValue(policy.getConfigurationAsync().get())
 
 	if config != "" {
 		t.Error("Expecting default")
@@ -64,14 +64,11 @@ func TestAutoPollingPolicy_GetConfigurationAsync_Fail(t *testing.T) {
 func TestAutoPollingPolicy_GetConfigurationAsync_WithListener(t *testing.T) {
 	fetcher := newFakeConfigProvider()
 	logger := DefaultLogger(LogLevelWarn)
-	fetcher.SetResponse(fetchResponse{status: Fetched, body: "test"})
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test")})
 	c := make(chan bool, 1)
 	defer close(c)
 	policy := newAutoPollingPolicy(
-		fetcher,
-		newInMemoryConfigCache(),
-		logger,
-		"",
+		newConfigRefresher(fetcher, NewInMemoryCache(), logger, ""),
 		AutoPollWithChangeListener(
 			time.Second*2,
 			func() { c <- true },
@@ -84,3 +81,74 @@ func TestAutoPollingPolicy_GetConfigurationAsync_WithListener(t *testing.T) {
 		t.Error("Expecting test as result")
 	}
 }
+
+func TestAutoPollingPolicy_SkipsInitialPollWithFreshCache(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("fetched")})
+	cache := NewInMemoryCache()
+	refresher := newConfigRefresher(fetcher, cache, DefaultLogger(LogLevelWarn), "")
+	cached := &config{jsonBody: testConfigJson("cached"), fetchTime: time.Now()}
+	cache.Set(context.Background(), refresher.cacheKey, cached.cacheEntry())
+
+	policy := newAutoPollingPolicy(refresher, autoPollConfig{autoPollInterval: time.Minute})
+	defer policy.close()
+	config := configValue(policy.getConfigurationAsync().get())
+
+	if config != "cached" {
+		t.Errorf("Expecting the fresh cached configuration, got %q", config)
+	}
+}
+
+func TestAutoPollingPolicy_Jitter(t *testing.T) {
+	newPolicy := func() *autoPollingPolicy {
+		refresher := newConfigRefresher(newFakeConfigProvider(), NewInMemoryCache(), DefaultLogger(LogLevelWarn), "")
+		refresher.random = newLockedRand(rand.NewSource(1))
+		return newAutoPollingPolicy(refresher, autoPollConfig{autoPollInterval: time.Hour, jitter: 0.1})
+	}
+	policy, other := newPolicy(), newPolicy()
+	defer policy.close()
+	defer other.close()
+
+	for i := 0; i < 10; i++ {
+		delay := policy.nextPollDelay()
+		if delay < 54*time.Minute || delay > 66*time.Minute {
+			t.Fatalf("Expecting the delay to be within the jitter bounds, got %v", delay)
+		}
+		if otherDelay := other.nextPollDelay(); otherDelay != delay {
+			t.Errorf("Expecting the same delays from the same random source, got %v and %v", delay, otherDelay)
+		}
+	}
+}
+
+func TestAutoPollingPolicy_JitterIsLimited(t *testing.T) {
+	refresher := newConfigRefresher(newFakeConfigProvider(), NewInMemoryCache(), DefaultLogger(LogLevelWarn), "")
+	refresher.random = newLockedRand(rand.NewSource(2))
+	policy := newAutoPollingPolicy(refresher, autoPollConfig{autoPollInterval: time.Minute, jitter: 3})
+	defer policy.close()
+
+	for i := 0; i < 100; i++ {
+		if delay := policy.nextPollDelay(); delay < 0 || delay > 2*time.Minute {
+			t.Fatalf("Expecting the jitter to be limited to the interval, got %v", delay)
+		}
+	}
+}
+
+func TestAutoPollingPolicy_RandomizeFirstPoll(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("fetched")})
+	cache := NewInMemoryCache()
+	refresher := newConfigRefresher(fetcher, cache, DefaultLogger(LogLevelWarn), "")
+	cached := &config{jsonBody: testConfigJson("cached"), fetchTime: time.Now().Add(-2 * time.Hour)}
+	cache.Set(context.Background(), refresher.cacheKey, cached.cacheEntry())
+
+	policy := newAutoPollingPolicy(refresher, autoPollConfig{autoPollInterval: time.Hour, randomizeFirstPoll: true})
+	defer policy.close()
+
+	if config := configValue(policy.getConfigurationAsync().get()); config != "cached" {
+		t.Errorf("Expecting the cached configuration until the first poll, got %q", config)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if count := fetcher.fetchCount(); count != 0 {
+		t.Errorf("Expecting the first poll to be delayed, got %d fetches", count)
+	}
+}
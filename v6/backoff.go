@@ -0,0 +1,77 @@
+package configcat
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Backoff describes how the fetches are delayed after failures. After the first failure
+// the next fetch is delayed by InitialDelay, and the delay is multiplied by Multiplier
+// after each further failure, up to MaxDelay. A successful fetch resets the delay.
+// The zero fields are set to their defaults.
+type Backoff struct {
+	// The delay after the first failure. Default: 1s.
+	InitialDelay time.Duration
+	// The factor the delay is multiplied by after each further failure. Default: 2.
+	Multiplier float64
+	// The maximum delay. Default: 5m.
+	MaxDelay time.Duration
+	// The fraction of the delay which is randomized, between 0 and 1, so the clients
+	// don't retry in lockstep. For example with 0.5, a delay of 10s becomes
+	// a random delay between 5s and 15s. Default: 0, no randomization.
+	Jitter float64
+}
+
+// backoffState tracks the failed fetches of a configRefresher.
+type backoffState struct {
+	config   Backoff
+	random   *lockedRand
+	clock    Clock
+	mu       sync.Mutex
+	failures int
+	// next holds the time before which no fetch should be made.
+	next time.Time
+}
+
+func newBackoffState(config Backoff, random *lockedRand, clock Clock) *backoffState {
+	if config.InitialDelay <= 0 {
+		config.InitialDelay = time.Second
+	}
+	if config.Multiplier < 1 {
+		config.Multiplier = 2
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = 5 * time.Minute
+	}
+	config.Jitter = math.Max(0, math.Min(1, config.Jitter))
+	return &backoffState{config: config, random: random, clock: clock}
+}
+
+// record updates the state with the outcome of a fetch.
+func (state *backoffState) record(response fetchResponse) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if !response.isFailed() {
+		state.failures = 0
+		state.next = time.Time{}
+		return
+	}
+
+	state.failures++
+	state.next = state.clock.Now().Add(state.delay())
+}
+
+// delay returns the delay belonging to the current number of failures.
+func (state *backoffState) delay() time.Duration {
+	delay := float64(state.config.InitialDelay) * math.Pow(state.config.Multiplier, float64(state.failures-1))
+	delay = math.Min(delay, float64(state.config.MaxDelay))
+	return state.random.jitter(time.Duration(delay), state.config.Jitter)
+}
+
+// active reports whether fetches should be delayed because of earlier failures.
+func (state *backoffState) active() bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.clock.Now().Before(state.next)
+}
@@ -0,0 +1,77 @@
+package configcat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	state := newBackoffState(Backoff{InitialDelay: time.Second, Multiplier: 3, MaxDelay: 20 * time.Second}, newLockedRand(nil), realClock{})
+
+	expected := []time.Duration{time.Second, 3 * time.Second, 9 * time.Second, 20 * time.Second, 20 * time.Second}
+	for _, delay := range expected {
+		state.record(fetchResponse{status: Failure})
+		if actual := state.delay(); actual != delay {
+			t.Errorf("Expecting delay %v, got %v", delay, actual)
+		}
+	}
+	if !state.active() {
+		t.Error("Expecting the backoff to be active after failures")
+	}
+
+	state.record(fetchResponse{status: NotModified})
+	if state.active() || state.failures != 0 {
+		t.Error("Expecting the backoff to be reset after a successful fetch")
+	}
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	state := newBackoffState(Backoff{InitialDelay: 10 * time.Second, Jitter: 0.5}, newLockedRand(nil), realClock{})
+	state.record(fetchResponse{status: Failure})
+
+	for i := 0; i < 100; i++ {
+		if delay := state.delay(); delay < 5*time.Second || delay > 15*time.Second {
+			t.Fatalf("Expecting the delay to be within the jitter bounds, got %v", delay)
+		}
+	}
+}
+
+func TestBackoff_LazyLoadDoesNotRetryOnEveryCall(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Failure})
+	clock := NewManualClock(time.Now())
+	client := newInternal("fakeKey", ClientConfig{
+		Mode:    LazyLoad(time.Minute, false),
+		Backoff: &Backoff{InitialDelay: 100 * time.Millisecond},
+		Clock:   clock,
+	}, fetcher)
+	defer client.Close()
+
+	for i := 0; i < 10; i++ {
+		client.GetValue("key", "default")
+	}
+	if count := fetcher.fetchCount(); count != 1 {
+		t.Errorf("Expecting a single fetch while backing off, got %d", count)
+	}
+
+	clock.Advance(150 * time.Millisecond)
+	client.GetValue("key", "default")
+	if count := fetcher.fetchCount(); count != 2 {
+		t.Errorf("Expecting a fetch after the delay, got %d", count)
+	}
+}
+
+func TestBackoff_AutoPollSkipsPolls(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Failure})
+	client := newInternal("fakeKey", ClientConfig{
+		Mode:    AutoPoll(10 * time.Millisecond),
+		Backoff: &Backoff{InitialDelay: time.Minute},
+	}, fetcher)
+	defer client.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	if count := fetcher.fetchCount(); count != 1 {
+		t.Errorf("Expecting the polls to be skipped while backing off, got %d fetches", count)
+	}
+}
@@ -0,0 +1,123 @@
+package configcat
+
+import (
+	"sync"
+)
+
+// changeBroadcaster delivers configuration changes to the subscribed listeners.
+// The changes are delivered in order on a dedicated goroutine, so a slow
+// listener never blocks the refresh policies, and a panicking listener
+// doesn't affect the other ones. While the listeners are busy, the new
+// changes are merged into one pending change, so a stuck listener
+// doesn't make the queue grow.
+type changeBroadcaster struct {
+	logger    Logger
+	mu        sync.Mutex
+	listeners []changeSubscription
+	nextId    int
+	pending   *ConfigChange
+	notify    chan struct{}
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+type changeSubscription struct {
+	id       int
+	listener func(change *ConfigChange)
+}
+
+func newChangeBroadcaster(logger Logger) *changeBroadcaster {
+	broadcaster := &changeBroadcaster{
+		logger: logger,
+		notify: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	go broadcaster.run()
+	return broadcaster
+}
+
+// subscribe registers a listener and returns a function which removes it.
+func (broadcaster *changeBroadcaster) subscribe(listener func(change *ConfigChange)) func() {
+	broadcaster.mu.Lock()
+	defer broadcaster.mu.Unlock()
+	id := broadcaster.nextId
+	broadcaster.nextId++
+	broadcaster.listeners = append(broadcaster.listeners, changeSubscription{id: id, listener: listener})
+
+	return func() {
+		broadcaster.mu.Lock()
+		defer broadcaster.mu.Unlock()
+		for i, subscription := range broadcaster.listeners {
+			if subscription.id == id {
+				broadcaster.listeners = append(broadcaster.listeners[:i:i], broadcaster.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// hasListeners reports whether there is any subscribed listener.
+func (broadcaster *changeBroadcaster) hasListeners() bool {
+	broadcaster.mu.Lock()
+	defer broadcaster.mu.Unlock()
+	return len(broadcaster.listeners) > 0
+}
+
+// publish queues the change for delivery without waiting for the listeners.
+// When a change is already waiting for delivery, the two are merged into one,
+// which is dropped when the second change reverted the first one.
+func (broadcaster *changeBroadcaster) publish(change *ConfigChange) {
+	broadcaster.mu.Lock()
+	if broadcaster.pending != nil {
+		change = mergeChanges(broadcaster.pending, change)
+		if len(change.Added) == 0 && len(change.Removed) == 0 && len(change.Modified) == 0 {
+			change = nil
+		}
+	}
+	broadcaster.pending = change
+	broadcaster.mu.Unlock()
+
+	select {
+	case broadcaster.notify <- struct{}{}:
+	default:
+	}
+}
+
+// close stops the delivery. The changes not delivered yet are dropped.
+func (broadcaster *changeBroadcaster) close() {
+	broadcaster.stopOnce.Do(func() {
+		close(broadcaster.stop)
+	})
+}
+
+func (broadcaster *changeBroadcaster) run() {
+	for {
+		select {
+		case <-broadcaster.stop:
+			return
+		case <-broadcaster.notify:
+		}
+
+		broadcaster.mu.Lock()
+		change := broadcaster.pending
+		broadcaster.pending = nil
+		listeners := broadcaster.listeners
+		broadcaster.mu.Unlock()
+
+		if change == nil {
+			continue
+		}
+		for _, subscription := range listeners {
+			broadcaster.deliver(subscription.listener, change)
+		}
+	}
+}
+
+func (broadcaster *changeBroadcaster) deliver(listener func(change *ConfigChange), change *ConfigChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			broadcaster.logger.Errorf("Config change listener panicked: %v", r)
+		}
+	}()
+	listener(change)
+}
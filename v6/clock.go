@@ -0,0 +1,119 @@
+package configcat
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells the time to the client: the ages of the cached configurations, the poll
+// intervals, the fetch timeouts and the waits of the synchronous calls are measured with it.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel which receives the current time after the duration elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock of the wall time.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// withClockTimeout returns a copy of ctx which is cancelled when the duration elapsed on the clock.
+// Its Err returns context.DeadlineExceeded after the timeout, like the contexts of context.WithTimeout.
+func withClockTimeout(ctx context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(realClock); ok {
+		// The timer of context.WithTimeout is released when it's cancelled, unlike the one of time.After.
+		return context.WithTimeout(ctx, d)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	timeoutCtx := &clockTimeoutContext{Context: ctx}
+	timeout := clock.After(d)
+	go func() {
+		select {
+		case <-timeout:
+			atomic.StoreUint32(&timeoutCtx.timedOut, yes)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return timeoutCtx, cancel
+}
+
+// clockTimeoutContext is the context of withClockTimeout.
+type clockTimeoutContext struct {
+	context.Context
+	timedOut uint32
+}
+
+func (ctx *clockTimeoutContext) Err() error {
+	if atomic.LoadUint32(&ctx.timedOut) == yes {
+		return context.DeadlineExceeded
+	}
+	return ctx.Context.Err()
+}
+
+// ManualClock is a Clock which only moves when it's advanced, so the time dependent
+// behaviour of the refresh policies can be tested deterministically.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []manualClockWaiter
+}
+
+type manualClockWaiter struct {
+	deadline time.Time
+	c        chan time.Time
+}
+
+// NewManualClock creates a ManualClock set to the given time.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (clock *ManualClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+// After returns a channel which receives the time of the clock
+// when it's advanced by at least the duration.
+func (clock *ManualClock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- clock.now
+		return c
+	}
+	clock.waiters = append(clock.waiters, manualClockWaiter{deadline: clock.now.Add(d), c: c})
+	return c
+}
+
+// Advance moves the clock forward by the duration, firing the channels
+// returned by After whose duration elapsed.
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+	waiters := clock.waiters[:0]
+	for _, waiter := range clock.waiters {
+		if waiter.deadline.After(clock.now) {
+			waiters = append(waiters, waiter)
+			continue
+		}
+		waiter.c <- clock.now
+	}
+	clock.waiters = waiters
+}
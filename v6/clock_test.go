@@ -0,0 +1,90 @@
+package configcat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManualClock_After(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	clock := NewManualClock(start)
+	c := clock.After(time.Minute)
+
+	clock.Advance(30 * time.Second)
+	select {
+	case <-c:
+		t.Fatal("Expecting the channel not to fire before the duration elapsed")
+	default:
+	}
+
+	clock.Advance(30 * time.Second)
+	select {
+	case now := <-c:
+		if !now.Equal(start.Add(time.Minute)) {
+			t.Errorf("Expecting the time of the clock, got %v", now)
+		}
+	default:
+		t.Fatal("Expecting the channel to fire after the duration elapsed")
+	}
+
+	select {
+	case <-clock.After(0):
+	default:
+		t.Error("Expecting a zero duration to fire immediately")
+	}
+}
+
+func TestClient_Clock_ExpiresLazyLoadedConfig(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test")})
+	clock := NewManualClock(time.Now())
+	client := newInternal("fakeKey", ClientConfig{Mode: LazyLoad(time.Hour, false), Clock: clock}, fetcher)
+	defer client.Close()
+
+	if value := client.GetValue("key", ""); value != "test" {
+		t.Fatalf("Expecting test as result, got %v", value)
+	}
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test2")})
+	clock.Advance(59 * time.Minute)
+	if value := client.GetValue("key", ""); value != "test" {
+		t.Errorf("Expecting the cached value before the cache interval elapsed, got %v", value)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if value := client.GetValue("key", ""); value != "test2" {
+		t.Errorf("Expecting the refreshed value after the cache interval elapsed, got %v", value)
+	}
+}
+
+func TestClient_Clock_BoundsSyncCalls(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponseWithDelay(fetchResponse{status: Fetched, body: testConfigJson("test")}, 10*time.Second)
+	clock := NewManualClock(time.Now())
+	client := newInternal("fakeKey", ClientConfig{Mode: ManualPoll(), MaxWaitTimeForSyncCalls: time.Minute, Clock: clock}, fetcher)
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.RefreshCtx(context.Background())
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expecting the refresh to wait for the clock, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expecting deadline exceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expecting the refresh to time out when the clock is advanced")
+	}
+}
@@ -0,0 +1,149 @@
+package configcat
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// config holds a parsed configuration together with the JSON it was parsed from.
+// A config is immutable once created, so it can be shared between goroutines freely.
+type config struct {
+	jsonBody  string
+	root      *Config
+	fetchTime time.Time
+	// eTag holds the ETag of the HTTP response the configuration was fetched with.
+	eTag string
+}
+
+// Config describes a configuration in the config_v5 format downloaded from ConfigCat.
+// It can be read from and written to the configuration JSON with the encoding/json package.
+type Config struct {
+	// Settings holds the feature flags and settings keyed by their keys.
+	Settings map[string]*Setting `json:"f"`
+	// Preferences holds the data governance preferences, if any.
+	Preferences *Preferences `json:"p,omitempty"`
+}
+
+// Preferences holds the data governance related preferences of the configuration.
+type Preferences struct {
+	// URL holds the base URL the clients should fetch the configuration from.
+	URL string `json:"u"`
+	// Redirect is one of NoRedirect, ShouldRedirect and ForceRedirect.
+	Redirect int `json:"r"`
+}
+
+// Setting describes a single feature flag or setting.
+type Setting struct {
+	// Value holds the value served when no targeting rule or percentage option applies.
+	// The numbers are stored as float64, regardless of the type of the setting.
+	Value interface{} `json:"v"`
+	// Type holds the type of the values of the setting,
+	// or UnknownSetting when the configuration doesn't tell it.
+	Type SettingType `json:"t"`
+	// PercentageOptions holds the percentage options, applied when no targeting rule matches.
+	PercentageOptions []*PercentageOption `json:"p"`
+	// RolloutRules holds the targeting rules, evaluated in order.
+	RolloutRules []*RolloutRule `json:"r"`
+	// VariationID identifies the value served when no targeting rule or percentage option applies.
+	VariationID string `json:"i"`
+}
+
+// RolloutRule describes a targeting rule of a setting.
+type RolloutRule struct {
+	// Value holds the value served when the rule matches.
+	Value interface{} `json:"v"`
+	// ComparisonAttribute holds the name of the user attribute the rule compares.
+	ComparisonAttribute string `json:"a"`
+	// Comparator identifies the comparison operator.
+	Comparator Comparator `json:"t"`
+	// ComparisonValue holds the value the user attribute is compared to.
+	ComparisonValue string `json:"c"`
+	// VariationID identifies the value served when the rule matches.
+	VariationID string `json:"i"`
+}
+
+// PercentageOption describes a percentage option of a setting.
+type PercentageOption struct {
+	// Value holds the value served for the users falling into this option.
+	Value interface{} `json:"v"`
+	// Percentage holds the share of users falling into this option.
+	Percentage int64 `json:"p"`
+	// VariationID identifies the value served by this option.
+	VariationID string `json:"i"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler so that a missing setting
+// type can be told apart from a boolean one.
+func (s *Setting) UnmarshalJSON(data []byte) error {
+	type plainSetting Setting
+	plain := plainSetting{Type: UnknownSetting}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return err
+	}
+	*s = Setting(plain)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler so that the type is omitted when it's
+// UnknownSetting, as it was missing from the configuration the setting was read from.
+func (s Setting) MarshalJSON() ([]byte, error) {
+	type plainSetting Setting
+	if s.Type != UnknownSetting {
+		return json.Marshal(plainSetting(s))
+	}
+	return json.Marshal(struct {
+		plainSetting
+		Type *SettingType `json:"t,omitempty"`
+	}{plainSetting: plainSetting(s)})
+}
+
+// parseConfig parses the given configuration JSON fetched at fetchTime.
+// The fetchTime is zero when it's unknown. Null settings, targeting rules
+// and percentage options are dropped, so the evaluation can skip the nil checks.
+func parseConfig(jsonBody string, fetchTime time.Time) (*config, error) {
+	var root Config
+	if err := json.Unmarshal([]byte(jsonBody), &root); err != nil {
+		return nil, err
+	}
+	root.dropNullEntries()
+	return &config{jsonBody: jsonBody, root: &root, fetchTime: fetchTime}, nil
+}
+
+func (root *Config) dropNullEntries() {
+	for key, setting := range root.Settings {
+		if setting == nil {
+			delete(root.Settings, key)
+			continue
+		}
+		rules := setting.RolloutRules[:0]
+		for _, rule := range setting.RolloutRules {
+			if rule != nil {
+				rules = append(rules, rule)
+			}
+		}
+		setting.RolloutRules = rules
+		options := setting.PercentageOptions[:0]
+		for _, option := range setting.PercentageOptions {
+			if option != nil {
+				options = append(options, option)
+			}
+		}
+		setting.PercentageOptions = options
+	}
+}
+
+// withFetchTime returns a copy of the configuration with the given fetch time.
+func (conf *config) withFetchTime(fetchTime time.Time) *config {
+	refreshed := *conf
+	refreshed.fetchTime = fetchTime
+	return &refreshed
+}
+
+// getAllKeys returns the keys of all the settings in the configuration.
+func (conf *config) getAllKeys() []string {
+	keys := make([]string, 0, len(conf.root.Settings))
+	for key := range conf.root.Settings {
+		keys = append(keys, key)
+	}
+	return keys
+}
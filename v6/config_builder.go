@@ -0,0 +1,187 @@
+package configcat
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConfigBuilder builds configurations in the format of the configuration downloaded
+// from ConfigCat, e.g. for flag override files and fake servers in tests.
+// The settings are validated when the configuration is built.
+type ConfigBuilder struct {
+	settings    map[string]*SettingBuilder
+	preferences *Preferences
+}
+
+// SettingBuilder builds a setting of a ConfigBuilder.
+type SettingBuilder struct {
+	defaultValue interface{}
+	variationID  string
+	rules        []*RolloutRule
+	percentages  []*PercentageOption
+	// lastVariationID points to the variation ID of the value added last.
+	lastVariationID *string
+}
+
+// NewConfigBuilder creates an empty ConfigBuilder.
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{settings: make(map[string]*SettingBuilder)}
+}
+
+// Setting adds a setting served with the default value when no targeting rule or percentage
+// option applies, replacing the setting with the same key, and returns the builder of the setting.
+// The type of the default value, one of bool, string, int or float64, determines the type of the setting.
+func (builder *ConfigBuilder) Setting(key string, defaultValue interface{}) *SettingBuilder {
+	setting := &SettingBuilder{defaultValue: defaultValue}
+	setting.lastVariationID = &setting.variationID
+	builder.settings[key] = setting
+	return setting
+}
+
+// Redirect sets the data governance preferences of the configuration, which point the clients to baseUrl.
+// The redirect is one of NoRedirect, ShouldRedirect and ForceRedirect.
+func (builder *ConfigBuilder) Redirect(baseUrl string, redirect int) *ConfigBuilder {
+	builder.preferences = &Preferences{URL: baseUrl, Redirect: redirect}
+	return builder
+}
+
+// JSON validates the settings and returns the configuration JSON.
+func (builder *ConfigBuilder) JSON() (string, error) {
+	root, err := builder.Config()
+	if err != nil {
+		return "", err
+	}
+	jsonBody, err := json.Marshal(root)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBody), nil
+}
+
+// Config validates the settings and returns the configuration.
+func (builder *ConfigBuilder) Config() (*Config, error) {
+	keys := make([]string, 0, len(builder.settings))
+	for key := range builder.settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	root := &Config{Settings: make(map[string]*Setting, len(keys)), Preferences: builder.preferences}
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, fmt.Errorf("empty setting key")
+		}
+		setting, err := builder.settings[key].build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid setting %s: %v", key, err)
+		}
+		root.Settings[key] = setting
+	}
+	return root, nil
+}
+
+// Rule adds a targeting rule serving the value when the user attribute matches the comparison value.
+// The comparison values of OneOfSensitive and NotOneOfSensitive are given in plain text;
+// they're hashed when the configuration is built.
+func (builder *SettingBuilder) Rule(attribute string, comparator Comparator, comparisonValue string, value interface{}) *SettingBuilder {
+	rule := &RolloutRule{
+		Value:               value,
+		ComparisonAttribute: attribute,
+		Comparator:          comparator,
+		ComparisonValue:     comparisonValue,
+	}
+	builder.rules = append(builder.rules, rule)
+	builder.lastVariationID = &rule.VariationID
+	return builder
+}
+
+// Percentage adds a percentage option serving the value to the given share of the users
+// not matched by any targeting rule. The percentages of a setting must add up to 100.
+func (builder *SettingBuilder) Percentage(percentage int64, value interface{}) *SettingBuilder {
+	option := &PercentageOption{Value: value, Percentage: percentage}
+	builder.percentages = append(builder.percentages, option)
+	builder.lastVariationID = &option.VariationID
+	return builder
+}
+
+// WithVariationID sets the variation ID of the value added last: the default value,
+// or the value of the last targeting rule or percentage option.
+func (builder *SettingBuilder) WithVariationID(variationID string) *SettingBuilder {
+	*builder.lastVariationID = variationID
+	return builder
+}
+
+func (builder *SettingBuilder) build() (*Setting, error) {
+	result, ok := settingFromValue(builder.defaultValue)
+	if !ok {
+		return nil, fmt.Errorf("unsupported default value %v (%T)", builder.defaultValue, builder.defaultValue)
+	}
+	result.VariationID = builder.variationID
+	result.RolloutRules = []*RolloutRule{}
+	result.PercentageOptions = []*PercentageOption{}
+
+	for i, rule := range builder.rules {
+		value, err := checkValue(rule.Value, result.Type)
+		if err != nil {
+			return nil, fmt.Errorf("targeting rule %d: %v", i+1, err)
+		}
+		if len(rule.ComparisonAttribute) == 0 {
+			return nil, fmt.Errorf("targeting rule %d: empty comparison attribute", i+1)
+		}
+		if rule.Comparator < OneOf || rule.Comparator > NotOneOfSensitive {
+			return nil, fmt.Errorf("targeting rule %d: unknown comparator %d", i+1, rule.Comparator)
+		}
+		built := *rule
+		built.Value = value
+		if rule.Comparator == OneOfSensitive || rule.Comparator == NotOneOfSensitive {
+			built.ComparisonValue = hashComparisonValues(rule.ComparisonValue)
+		}
+		result.RolloutRules = append(result.RolloutRules, &built)
+	}
+
+	total := int64(0)
+	for i, option := range builder.percentages {
+		value, err := checkValue(option.Value, result.Type)
+		if err != nil {
+			return nil, fmt.Errorf("percentage option %d: %v", i+1, err)
+		}
+		if option.Percentage < 0 {
+			return nil, fmt.Errorf("percentage option %d: negative percentage %d", i+1, option.Percentage)
+		}
+		total += option.Percentage
+		built := *option
+		built.Value = value
+		result.PercentageOptions = append(result.PercentageOptions, &built)
+	}
+	if len(builder.percentages) > 0 && total != 100 {
+		return nil, fmt.Errorf("the percentages add up to %d instead of 100", total)
+	}
+	return result, nil
+}
+
+// checkValue returns the value as stored in the configuration, or an error
+// when its type doesn't match the setting kind.
+func checkValue(value interface{}, kind SettingType) (interface{}, error) {
+	converted, ok := settingFromValue(value)
+	if !ok {
+		return nil, fmt.Errorf("unsupported value %v (%T)", value, value)
+	}
+	if converted.Type != kind {
+		return nil, fmt.Errorf("the value %v is %v, but the setting is %v", value, converted.Type, kind)
+	}
+	return converted.Value, nil
+}
+
+// hashComparisonValues hashes the comma separated values as the sensitive comparators expect them.
+func hashComparisonValues(values string) string {
+	hashed := strings.Split(values, ",")
+	for i, value := range hashed {
+		hash := sha1.Sum([]byte(strings.TrimSpace(value)))
+		hashed[i] = hex.EncodeToString(hash[:])
+	}
+	return strings.Join(hashed, ",")
+}
@@ -0,0 +1,98 @@
+package configcat
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConfigBuilder_JSON(t *testing.T) {
+	builder := NewConfigBuilder()
+	builder.Setting("bool", false).WithVariationID("off").
+		Rule("Email", Contains, "@example.com", true).WithVariationID("on")
+	builder.Setting("string", "public").
+		Rule("Identifier", OneOfSensitive, "alice, bob", "secret")
+	builder.Setting("int", 1).
+		Percentage(30, 2).WithVariationID("two").
+		Percentage(70, 3).WithVariationID("three")
+	builder.Setting("float", 1.5)
+
+	jsonBody, err := builder.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := parseConfig(jsonBody, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := newParser(DefaultLogger(LogLevelError))
+	user := NewUserWithAdditionalAttributes("bob", "bob@example.com", "", nil)
+	if value, _ := parser.parse(conf, "bool", user); value != true {
+		t.Errorf("Expecting the targeting rule to match, got %v", value)
+	}
+	if id, _ := parser.parseVariationId(conf, "bool", NewUser("carol")); id != "off" {
+		t.Errorf("Expecting the default variation, got %q", id)
+	}
+	if value, _ := parser.parse(conf, "string", user); value != "secret" {
+		t.Errorf("Expecting the sensitive rule to match, got %v", value)
+	}
+	if id, _ := parser.parseVariationId(conf, "int", user); id != "two" && id != "three" {
+		t.Errorf("Expecting a percentage option, got %q", id)
+	}
+	if value, _ := parser.parseTyped(conf, "float", FloatSetting, nil); value != 1.5 {
+		t.Errorf("Expecting the default value, got %v", value)
+	}
+}
+
+func TestConfigBuilder_Validates(t *testing.T) {
+	tests := map[string]func(builder *ConfigBuilder){
+		"empty key":           func(builder *ConfigBuilder) { builder.Setting("", true) },
+		"unsupported default": func(builder *ConfigBuilder) { builder.Setting("key", []string{}) },
+		"rule type mismatch": func(builder *ConfigBuilder) {
+			builder.Setting("key", true).Rule("Email", OneOf, "a@example.com", "true")
+		},
+		"unknown comparator": func(builder *ConfigBuilder) {
+			builder.Setting("key", true).Rule("Email", Comparator(18), "a@example.com", false)
+		},
+		"empty attribute": func(builder *ConfigBuilder) {
+			builder.Setting("key", true).Rule("", OneOf, "a@example.com", false)
+		},
+		"percentage sum": func(builder *ConfigBuilder) {
+			builder.Setting("key", 1).Percentage(30, 2).Percentage(30, 3)
+		},
+		"option type mismatch": func(builder *ConfigBuilder) {
+			builder.Setting("key", 1).Percentage(100, 2.5)
+		},
+	}
+	for name, test := range tests {
+		builder := NewConfigBuilder()
+		test(builder)
+		if _, err := builder.JSON(); err == nil {
+			t.Errorf("%s: expecting an error", name)
+		}
+	}
+}
+
+func TestConfigBuilder_FlagOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	builder := NewConfigBuilder()
+	builder.Setting("key", "default").Rule("Country", OneOf, "Hungary, Austria", "local")
+	jsonBody, err := builder.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeOverrideFile(t, dir, "config_v5.json", jsonBody)
+	_, client := newOverrideTestClient(&FlagOverrides{Behavior: LocalOnly, FilePath: path})
+	defer client.Close()
+
+	if value := client.GetValueForUser("key", "", NewUserWithAdditionalAttributes("id", "", "Austria", nil)); value != "local" {
+		t.Errorf("Expecting the targeting rule of the built configuration, got %v", value)
+	}
+}
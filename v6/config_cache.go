@@ -1,5 +1,14 @@
 package configcat
 
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
 // ConfigCache is a cache API used to make custom cache implementations.
 type ConfigCache interface {
 	// get reads the configuration from the cache.
@@ -8,22 +17,88 @@ type ConfigCache interface {
 	Set(key string, value string) error
 }
 
-type inMemoryConfigCache struct {
+// ConfigCacheCtx is a cache API used to make custom cache implementations
+// which can honour the deadlines and cancellation of the given contexts.
+type ConfigCacheCtx interface {
+	// Get reads the configuration from the cache.
+	// It returns an empty string when the key is not found.
+	Get(ctx context.Context, key string) (string, error)
+	// Set writes the configuration into the cache. The ctx isn't cancelled with the
+	// operation the configuration was fetched for, but it's bounded by a timeout.
+	Set(ctx context.Context, key string, value string) error
+}
+
+// contextCache adapts a ConfigCache to the ConfigCacheCtx interface.
+type contextCache struct {
+	cache ConfigCache
+}
+
+func (adapter contextCache) Get(ctx context.Context, key string) (string, error) {
+	return adapter.cache.Get(key)
+}
+
+func (adapter contextCache) Set(ctx context.Context, key string, value string) error {
+	return adapter.cache.Set(key, value)
+}
+
+// The cache entries are stored in the format "<fetch time>\n<ETag>\n<configuration JSON>",
+// where the fetch time is in Unix milliseconds. They're stored under the keys of CacheBase,
+// which differ from the keys of earlier SDK versions. Entries holding only the configuration
+// JSON, as stored by earlier versions, are read with an unknown fetch time.
+
+// cacheEntry serializes the configuration into a cache entry.
+func (conf *config) cacheEntry() string {
+	var millis int64
+	if !conf.fetchTime.IsZero() {
+		millis = conf.fetchTime.UnixNano() / int64(time.Millisecond)
+	}
+	return strconv.FormatInt(millis, 10) + "\n" + conf.eTag + "\n" + conf.jsonBody
+}
+
+// parseCacheEntry splits a cache entry into its fetch time, ETag and configuration JSON.
+func parseCacheEntry(entry string) (fetchTime time.Time, eTag string, jsonBody string, err error) {
+	if strings.HasPrefix(entry, "{") {
+		return time.Time{}, "", entry, nil
+	}
+
+	parts := strings.SplitN(entry, "\n", 3)
+	if len(parts) != 3 {
+		return time.Time{}, "", "", fmt.Errorf("invalid cache entry")
+	}
+	millis, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", "", fmt.Errorf("invalid fetch time in cache entry: %v", err)
+	}
+	if millis > 0 {
+		fetchTime = time.Unix(0, millis*int64(time.Millisecond))
+	}
+	return fetchTime, parts[1], parts[2], nil
+}
+
+// InMemoryCache is a ConfigCacheCtx which stores the configurations in memory.
+// It's safe for concurrent use, so it can be shared between clients, including
+// clients with different SDK keys.
+type InMemoryCache struct {
+	mu    sync.RWMutex
 	store map[string]string
 }
 
-// newInMemoryConfigCache creates an in-memory cache implementation used to store the fetched configurations.
-func newInMemoryConfigCache() *inMemoryConfigCache {
-	return &inMemoryConfigCache{store: make(map[string]string)}
+// NewInMemoryCache creates an in-memory cache implementation used to store the fetched configurations.
+func NewInMemoryCache() *InMemoryCache {
+	return &InMemoryCache{store: make(map[string]string)}
 }
 
-// get reads the configuration from the cache.
-func (cache *inMemoryConfigCache) Get(key string) (string, error) {
+// Get reads the configuration from the cache.
+func (cache *InMemoryCache) Get(ctx context.Context, key string) (string, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
 	return cache.store[key], nil
 }
 
-// set writes the configuration into the cache.
-func (cache *inMemoryConfigCache) Set(key string, value string) error {
+// Set writes the configuration into the cache.
+func (cache *InMemoryCache) Set(ctx context.Context, key string, value string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
 	cache.store[key] = value
 	return nil
 }
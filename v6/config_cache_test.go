@@ -0,0 +1,95 @@
+package configcat
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInMemoryCache_ConcurrentAccess(t *testing.T) {
+	cache := NewInMemoryCache()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i%3)
+			for j := 0; j < 100; j++ {
+				cache.Set(ctx, key, fmt.Sprintf("value%d", j))
+				if value, _ := cache.Get(ctx, key); value == "" {
+					t.Error("Expecting a value")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestInMemoryCache_SharedBetweenClients(t *testing.T) {
+	cache := NewInMemoryCache()
+	modes := []RefreshMode{ManualPoll(), AutoPoll(time.Millisecond), LazyLoad(time.Millisecond, true)}
+
+	var wg sync.WaitGroup
+	for i, mode := range modes {
+		for _, sdkKey := range []string{"first", "second"} {
+			fetcher := newFakeConfigProvider()
+			value := fmt.Sprintf("%s%d", sdkKey, i)
+			fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\""+sdkKey+"\"")})
+			client := newInternal(sdkKey, ClientConfig{Mode: mode, CacheCtx: cache}, fetcher)
+			defer client.Close()
+
+			wg.Add(1)
+			go func(sdkKey string) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\""+value+"\"")})
+					client.Refresh()
+					// The clients with the same SDK key share the cache entry, so any of their values can be read.
+					if got, _ := client.GetValue("key", "").(string); !strings.HasPrefix(got, sdkKey) {
+						t.Errorf("Expecting a value of the %s SDK key, got %q", sdkKey, got)
+					}
+					if got, _ := client.GetAllValues()["key"].(string); !strings.HasPrefix(got, sdkKey) {
+						t.Errorf("Expecting a value of the %s SDK key, got %q", sdkKey, got)
+					}
+				}
+			}(sdkKey)
+		}
+	}
+	wg.Wait()
+}
+
+func TestCacheKey_DiffersFromEarlierVersions(t *testing.T) {
+	refresher := newConfigRefresher(newFakeConfigProvider(), NewInMemoryCache(), DefaultLogger(LogLevelWarn), "fakeKey")
+	hash := sha1.Sum([]byte("fakeKey"))
+	legacyKey := "go_config_v5_" + hex.EncodeToString(hash[:])
+
+	if refresher.cacheKey == legacyKey {
+		t.Errorf("Expecting a cache key different from the one of earlier versions, got %s", refresher.cacheKey)
+	}
+}
+
+func TestCacheEntry(t *testing.T) {
+	fetchTime := time.Unix(1600000000, 123000000)
+	conf := &config{jsonBody: "{ \"f\": {} }", fetchTime: fetchTime, eTag: "\"etag\""}
+
+	parsedTime, eTag, jsonBody, err := parseCacheEntry(conf.cacheEntry())
+	if err != nil || !parsedTime.Equal(fetchTime) || eTag != conf.eTag || jsonBody != conf.jsonBody {
+		t.Errorf("Unexpected cache entry %v, %q, %q, %v", parsedTime, eTag, jsonBody, err)
+	}
+
+	parsedTime, eTag, jsonBody, err = parseCacheEntry("{ \"f\": {} }")
+	if err != nil || !parsedTime.IsZero() || eTag != "" || jsonBody != "{ \"f\": {} }" {
+		t.Errorf("Expecting a plain configuration JSON to be read, got %v, %q, %q, %v", parsedTime, eTag, jsonBody, err)
+	}
+
+	if _, _, _, err := parseCacheEntry("invalid"); err == nil {
+		t.Error("Expecting an invalid cache entry to fail")
+	}
+}
@@ -0,0 +1,64 @@
+package configcat
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ConfigChange describes a change of the configuration.
+type ConfigChange struct {
+	// Old holds the configuration before the change.
+	// It holds no settings when there was no configuration before.
+	Old *Snapshot
+	// New holds the configuration after the change.
+	New *Snapshot
+	// Added holds the keys of the settings that were added.
+	Added []string
+	// Removed holds the keys of the settings that were removed.
+	Removed []string
+	// Modified holds the keys of the settings whose value, type,
+	// targeting rules or percentage options were changed.
+	Modified []string
+}
+
+// diffConfigs collects the keys of the settings added, removed and modified between
+// the old and the new configuration. The returned keys are sorted.
+func diffConfigs(oldConfig, newConfig *config) (added, removed, modified []string) {
+	oldEntries := map[string]*Setting{}
+	if oldConfig != nil {
+		oldEntries = oldConfig.root.Settings
+	}
+	newEntries := newConfig.root.Settings
+
+	for key, newSetting := range newEntries {
+		oldSetting, ok := oldEntries[key]
+		if !ok {
+			added = append(added, key)
+		} else if !reflect.DeepEqual(oldSetting, newSetting) {
+			modified = append(modified, key)
+		}
+	}
+	for key := range oldEntries {
+		if _, ok := newEntries[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified
+}
+
+// mergeChanges merges two consecutive changes into one describing the change
+// from the configuration before the first one to the configuration after the second one.
+func mergeChanges(first, second *ConfigChange) *ConfigChange {
+	added, removed, modified := diffConfigs(first.Old.config, second.New.config)
+	return &ConfigChange{
+		Old:      first.Old,
+		New:      second.New,
+		Added:    added,
+		Removed:  removed,
+		Modified: modified,
+	}
+}
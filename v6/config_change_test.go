@@ -0,0 +1,45 @@
+package configcat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	oldConfig := mustParseConfig(t, `{ "f": {
+		"same": { "v": true, "t": 0, "p": [], "r": [] },
+		"removed": { "v": true, "t": 0, "p": [], "r": [] },
+		"value": { "v": 1, "t": 2, "p": [], "r": [] },
+		"rule": { "v": "a", "t": 1, "p": [], "r": [{ "v": "b", "a": "Email", "t": 2, "c": "x", "i": "1" }] }
+	}}`)
+	newConfig := mustParseConfig(t, `{ "f": {
+		"same": { "v": true, "t": 0, "p": [], "r": [] },
+		"added": { "v": true, "t": 0, "p": [], "r": [] },
+		"value": { "v": 2, "t": 2, "p": [], "r": [] },
+		"rule": { "v": "a", "t": 1, "p": [], "r": [{ "v": "b", "a": "Email", "t": 2, "c": "y", "i": "1" }] }
+	}}`)
+
+	added, removed, modified := diffConfigs(oldConfig, newConfig)
+
+	if !reflect.DeepEqual(added, []string{"added"}) {
+		t.Errorf("Unexpected added keys %v", added)
+	}
+
+	if !reflect.DeepEqual(removed, []string{"removed"}) {
+		t.Errorf("Unexpected removed keys %v", removed)
+	}
+
+	if !reflect.DeepEqual(modified, []string{"rule", "value"}) {
+		t.Errorf("Unexpected modified keys %v", modified)
+	}
+}
+
+func TestDiffConfigs_NoOldConfig(t *testing.T) {
+	newConfig := mustParseConfig(t, `{ "f": { "b": { "v": true }, "a": { "v": false } }}`)
+
+	added, removed, modified := diffConfigs(nil, newConfig)
+
+	if !reflect.DeepEqual(added, []string{"a", "b"}) || removed != nil || modified != nil {
+		t.Errorf("Unexpected diff %v %v %v", added, removed, modified)
+	}
+}
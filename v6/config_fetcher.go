@@ -1,6 +1,9 @@
 package configcat
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 )
@@ -14,11 +17,14 @@ const (
 )
 
 type configProvider interface {
-	getConfigurationAsync() *asyncResult
+	// getConfigurationAsync fetches the configuration. The fetch is aborted when ctx is done.
+	// When eTag is not empty, the response is NotModified if the configuration
+	// still has the same ETag.
+	getConfigurationAsync(ctx context.Context, eTag string) *asyncResult
 }
 
 type configFetcher struct {
-	sdkKey, eTag, mode, baseUrl string
+	sdkKey, mode, baseUrl string
 	urlIsCustom                 bool
 	parser                      *configParser
 	client                      *http.Client
@@ -49,37 +55,28 @@ func newConfigFetcher(sdkKey string, config ClientConfig, parser *configParser)
 	return fetcher
 }
 
-func (fetcher *configFetcher) getConfigurationAsync() *asyncResult {
-	return fetcher.executeFetchAsync(2)
+func (fetcher *configFetcher) getConfigurationAsync(ctx context.Context, eTag string) *asyncResult {
+	return fetcher.executeFetchAsync(ctx, eTag, 2)
 }
 
-func (fetcher *configFetcher) executeFetchAsync(executionCount int) *asyncResult {
-	return fetcher.sendFetchRequestAsync().compose(func(result interface{}) *asyncResult {
+func (fetcher *configFetcher) executeFetchAsync(ctx context.Context, eTag string, executionCount int) *asyncResult {
+	return fetcher.sendFetchRequestAsync(ctx, eTag).compose(func(result interface{}) *asyncResult {
 		fetchResponse, ok := result.(fetchResponse)
 		if !ok || !fetchResponse.isFetched() {
 			return asCompletedAsyncResult(result)
 		}
 
-		rootNode, err := fetcher.parser.deserialize(fetchResponse.body)
-		if err != nil {
+		preferences, err := fetcher.parsePreferences(fetchResponse.body)
+		if err != nil || preferences == nil {
 			return asCompletedAsyncResult(fetchResponse)
 		}
 
-		preferences, ok := rootNode[preferences].(map[string]interface{})
-		if !ok {
-			return asCompletedAsyncResult(fetchResponse)
-		}
-
-		newUrl, ok := preferences[preferencesUrl].(string)
-		if !ok || len(newUrl) == 0 || newUrl == fetcher.baseUrl {
-			return asCompletedAsyncResult(fetchResponse)
-		}
-
-		redirect, ok := preferences[preferencesRedirect].(float64)
-		if !ok {
+		newUrl := preferences.URL
+		if len(newUrl) == 0 || newUrl == fetcher.baseUrl {
 			return asCompletedAsyncResult(fetchResponse)
 		}
 
+		redirect := preferences.Redirect
 		if fetcher.urlIsCustom && redirect != ForceRedirect {
 			return asCompletedAsyncResult(fetchResponse)
 		}
@@ -96,7 +93,7 @@ func (fetcher *configFetcher) executeFetchAsync(executionCount int) *asyncResult
 			}
 
 			if executionCount > 0 {
-				return fetcher.executeFetchAsync(executionCount - 1)
+				return fetcher.executeFetchAsync(ctx, eTag, executionCount-1)
 			}
 		}
 
@@ -105,54 +102,67 @@ func (fetcher *configFetcher) executeFetchAsync(executionCount int) *asyncResult
 	})
 }
 
-func (fetcher *configFetcher) sendFetchRequestAsync() *asyncResult {
+// parsePreferences reads only the preferences section of the configuration JSON.
+func (fetcher *configFetcher) parsePreferences(jsonBody string) (*Preferences, error) {
+	var root struct {
+		Preferences *Preferences `json:"p"`
+	}
+	if err := json.Unmarshal([]byte(jsonBody), &root); err != nil {
+		return nil, err
+	}
+	return root.Preferences, nil
+}
+
+func (fetcher *configFetcher) sendFetchRequestAsync(ctx context.Context, eTag string) *asyncResult {
 	result := newAsyncResult()
 
 	go func() {
-		request, requestError := http.NewRequest("GET", fetcher.baseUrl+"/configuration-files/"+fetcher.sdkKey+"/"+ConfigJsonName+".json", nil)
+		request, requestError := http.NewRequestWithContext(ctx, "GET", fetcher.baseUrl+"/configuration-files/"+fetcher.sdkKey+"/"+ConfigJsonName+".json", nil)
 		if requestError != nil {
-			result.complete(fetchResponse{status: Failure})
+			result.complete(failedFetchResponse(0, requestError))
 			return
 		}
 
 		request.Header.Add("X-ConfigCat-UserAgent", "ConfigCat-Go/"+fetcher.mode+"-"+version)
 
-		if fetcher.eTag != "" {
-			request.Header.Add("If-None-Match", fetcher.eTag)
+		if eTag != "" {
+			request.Header.Add("If-None-Match", eTag)
 		}
 
 		response, responseError := fetcher.client.Do(request)
 		if responseError != nil {
 			fetcher.logger.Errorf("Config fetch failed: %s.", responseError.Error())
-			result.complete(fetchResponse{status: Failure, body: ""})
+			result.complete(failedFetchResponse(0, responseError))
 			return
 		}
 
-		defer response.Body.Close()
-
+		// The body is closed before completing the result, so the transport
+		// is done with the request by the time the waiters are released.
 		if response.StatusCode == 304 {
+			response.Body.Close()
 			fetcher.logger.Debugln("Config fetch succeeded: not modified.")
-			result.complete(fetchResponse{status: NotModified})
+			result.complete(fetchResponse{status: NotModified, statusCode: response.StatusCode})
 			return
 		}
 
 		if response.StatusCode >= 200 && response.StatusCode < 300 {
 			body, bodyError := ioutil.ReadAll(response.Body)
+			response.Body.Close()
 			if bodyError != nil {
 				fetcher.logger.Errorf("Config fetch failed: %s.", bodyError.Error())
-				result.complete(fetchResponse{status: Failure})
+				result.complete(failedFetchResponse(response.StatusCode, bodyError))
 				return
 			}
 
 			fetcher.logger.Debugln("Config fetch succeeded: new config fetched.")
-			fetcher.eTag = response.Header.Get("Etag")
-			result.complete(fetchResponse{status: Fetched, body: string(body)})
+			result.complete(fetchResponse{status: Fetched, body: string(body), eTag: response.Header.Get("Etag"), statusCode: response.StatusCode})
 			return
 		}
 
+		response.Body.Close()
 		fetcher.logger.Errorf("Double-check your SDK KEY at https://app.configcat.com/sdkkey. "+
 			"Received unexpected response: %v.", response.StatusCode)
-		result.complete(fetchResponse{status: Failure})
+		result.complete(failedFetchResponse(response.StatusCode, fmt.Errorf("unexpected response: %s", http.StatusText(response.StatusCode))))
 	}()
 
 	return result
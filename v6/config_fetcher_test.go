@@ -2,11 +2,13 @@ package configcat
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 )
 
 const jsonTemplate = "{ \"p\": { \"u\": \"%s\", \"r\": %d }, \"f\": {} }"
@@ -15,13 +17,13 @@ const customCdnUrl = "https://custom-cdn.configcat.com"
 func TestConfigFetcher_GetConfigurationJson(t *testing.T) {
 	fetcher := newConfigFetcher("PKDVCLf-Hq-h-kCzMp-L7Q/PaDVCFk9EpmD6sLpGLltTA",
 		defaultConfig(), newParser(DefaultLogger(LogLevelError)))
-	response := fetcher.getConfigurationAsync().get().(fetchResponse)
+	response := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse)
 
 	if !response.isFetched() {
 		t.Error("Expecting fetched")
 	}
 
-	response2 := fetcher.getConfigurationAsync().get().(fetchResponse)
+	response2 := fetcher.getConfigurationAsync(context.Background(), response.eTag).get().(fetchResponse)
 
 	if !response2.isNotModified() {
 		t.Error("Expecting not modified")
@@ -31,7 +33,7 @@ func TestConfigFetcher_GetConfigurationJson(t *testing.T) {
 func TestConfigFetcher_GetConfigurationJson_Fail(t *testing.T) {
 	fetcher := newConfigFetcher("thisshouldnotexist", defaultConfig(),
 		newParser(DefaultLogger(LogLevelError)))
-	response := fetcher.getConfigurationAsync().get().(fetchResponse)
+	response := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse)
 
 	if !response.isFailed() {
 		t.Error("Expecting failed")
@@ -47,7 +49,7 @@ func TestConfigFetcher_ShouldStayOnGivenUrl(t *testing.T) {
 	fetcher := createFetcher(transport, "")
 
 	// Act
-	result := fetcher.getConfigurationAsync().get().(fetchResponse).body
+	result := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse).body
 
 	// Assert
 	if body != result {
@@ -72,7 +74,7 @@ func TestConfigFetcher_ShouldStayOnSameUrlWithRedirect(t *testing.T) {
 	fetcher := createFetcher(transport, "")
 
 	// Act
-	result := fetcher.getConfigurationAsync().get().(fetchResponse).body
+	result := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse).body
 
 	// Assert
 	if body != result {
@@ -97,7 +99,7 @@ func TestConfigFetcher_ShouldStayOnSameUrlEvenWhenForced(t *testing.T) {
 	fetcher := createFetcher(transport, "")
 
 	// Act
-	result := fetcher.getConfigurationAsync().get().(fetchResponse).body
+	result := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse).body
 
 	// Assert
 	if body != result {
@@ -124,7 +126,7 @@ func TestConfigFetcher_ShouldRedirectToAnotherServer(t *testing.T) {
 	fetcher := createFetcher(transport, "")
 
 	// Act
-	result := fetcher.getConfigurationAsync().get().(fetchResponse).body
+	result := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse).body
 
 	// Assert
 	if body2 != result {
@@ -155,7 +157,7 @@ func TestConfigFetcher_ShouldRedirectToAnotherServerWhenForced(t *testing.T) {
 	fetcher := createFetcher(transport, "")
 
 	// Act
-	result := fetcher.getConfigurationAsync().get().(fetchResponse).body
+	result := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse).body
 
 	// Assert
 	if body2 != result {
@@ -187,7 +189,7 @@ func TestConfigFetcher_ShouldBreakRedirectLoop(t *testing.T) {
 	fetcher := createFetcher(transport, "")
 
 	// Act
-	result := fetcher.getConfigurationAsync().get().(fetchResponse).body
+	result := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse).body
 
 	// Assert
 	if body1 != result {
@@ -220,7 +222,7 @@ func TestConfigFetcher_ShouldRespectCustomUrlWhenNotForced(t *testing.T) {
 	fetcher := createFetcher(transport, customCdnUrl)
 
 	// Act
-	result := fetcher.getConfigurationAsync().get().(fetchResponse).body
+	result := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse).body
 
 	// Assert
 	if body != result {
@@ -247,7 +249,7 @@ func TestConfigFetcher_ShouldNotRespectCustomUrlWhenForced(t *testing.T) {
 	fetcher := createFetcher(transport, customCdnUrl)
 
 	// Act
-	result := fetcher.getConfigurationAsync().get().(fetchResponse).body
+	result := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse).body
 
 	// Assert
 	if body2 != result {
@@ -297,3 +299,67 @@ func (m *mockHttpTransport) enqueue(statusCode int, body string) {
 		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
 	})
 }
+
+func TestConfigFetcher_ShouldAbortRequestWhenContextIsDone(t *testing.T) {
+	transport := &blockingHttpTransport{cancelled: make(chan struct{})}
+	fetcher := createFetcher(transport, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := fetcher.getConfigurationAsync(ctx, "")
+	cancel()
+
+	if !result.get().(fetchResponse).isFailed() {
+		t.Error("Expecting failed")
+	}
+
+	select {
+	case <-transport.cancelled:
+	case <-time.After(time.Second):
+		t.Error("Expecting the request to be cancelled")
+	}
+}
+
+// blockingHttpTransport blocks every request until its context is done.
+type blockingHttpTransport struct {
+	cancelled chan struct{}
+}
+
+func (m *blockingHttpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	close(m.cancelled)
+	return nil, req.Context().Err()
+}
+
+func TestConfigFetcher_ShouldReportStatusCodeOfUnexpectedResponse(t *testing.T) {
+	transport := newMockHttpTransport()
+	transport.enqueue(404, "")
+	fetcher := createFetcher(transport, "")
+
+	response := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse)
+
+	fetchErr, ok := response.fetchErr().(*FetchError)
+	if !response.isFailed() || !ok || fetchErr.StatusCode != 404 {
+		t.Errorf("Expecting failure with status code 404, got %v", response.fetchErr())
+	}
+}
+
+func TestConfigFetcher_ShouldSendETag(t *testing.T) {
+	transport := newMockHttpTransport()
+	transport.enqueue(200, "{ \"f\": {} }")
+	transport.responses[0].Header = http.Header{"Etag": []string{"\"etag1\""}}
+	transport.enqueue(304, "")
+	fetcher := createFetcher(transport, "")
+
+	response := fetcher.getConfigurationAsync(context.Background(), "").get().(fetchResponse)
+	if !response.isFetched() || response.eTag != "\"etag1\"" {
+		t.Fatalf("Expecting fetched with ETag, got %+v", response)
+	}
+
+	response = fetcher.getConfigurationAsync(context.Background(), response.eTag).get().(fetchResponse)
+	if !response.isNotModified() {
+		t.Errorf("Expecting not modified, got %+v", response)
+	}
+	if header := transport.requests[1].Header.Get("If-None-Match"); header != "\"etag1\"" {
+		t.Errorf("Expecting the If-None-Match header to hold the ETag, got %q", header)
+	}
+}
@@ -1,7 +1,8 @@
 package configcat
 
 import (
-	"encoding/json"
+	"fmt"
+	"math"
 	"strings"
 )
 
@@ -13,6 +14,8 @@ func (p *parseError) Error() string {
 	return p.msg
 }
 
+var errConfigMissing = &parseError{"Config JSON is not present."}
+
 type configParser struct {
 	evaluator *rolloutEvaluator
 	logger    Logger
@@ -23,121 +26,143 @@ func newParser(logger Logger) *configParser {
 	return &configParser{evaluator: evaluator, logger: logger}
 }
 
-func (parser *configParser) parse(jsonBody string, key string, user *User) (interface{}, error) {
-	result, _, err := parser.parseInternal(jsonBody, key, user)
-	return result, err
+func (parser *configParser) parse(conf *config, key string, user *User) (interface{}, error) {
+	details, _ := parser.parseDetails(conf, key, user)
+	return details.Value, details.Error
 }
 
-func (parser *configParser) parseVariationId(jsonBody string, key string, user *User) (string, error) {
-	_, variationId, err := parser.parseInternal(jsonBody, key, user)
-	return variationId, err
+// parseTyped evaluates the setting identified by key and converts the result to the Go type
+// belonging to the expected setting kind. It fails when the type of the setting
+// in the configuration doesn't match the expected one.
+func (parser *configParser) parseTyped(conf *config, key string, expected SettingType, user *User) (interface{}, error) {
+	details := parser.parseTypedDetails(conf, key, expected, user)
+	return details.Value, details.Error
 }
 
-func (parser *configParser) getAllKeys(jsonBody string) ([]string, error) {
-	rootNode, err := parser.getEntries(jsonBody)
-	if err != nil {
-		return nil, err
+// parseTypedDetails is like parseTyped but returns the details of the evaluation.
+// On failure the Error field of the returned details is set and its Value is nil.
+func (parser *configParser) parseTypedDetails(conf *config, key string, expected SettingType, user *User) EvaluationDetails {
+	details, kind := parser.parseDetails(conf, key, user)
+	if details.Error != nil {
+		return details
+	}
+
+	result := details.Value
+	details.Value = nil
+	if kind != UnknownSetting && kind != expected {
+		details.Error = &parseError{fmt.Sprintf("Type mismatch for key %s: the setting is of type %v but %v was requested", key, kind, expected)}
+		return details
+	}
+
+	converted, ok := convertValue(result, expected)
+	if !ok {
+		details.Error = &parseError{fmt.Sprintf("Type mismatch for key %s: cannot convert %v (%T) to %v", key, result, result, expected)}
+		return details
 	}
 
-	keys := make([]string, len(rootNode))
-	i := 0
-	for k := range rootNode {
-		keys[i] = k
-		i++
+	details.Value = converted
+	return details
+}
+
+func (parser *configParser) parseVariationId(conf *config, key string, user *User) (string, error) {
+	details, _ := parser.parseDetails(conf, key, user)
+	return details.VariationID, details.Error
+}
+
+func (parser *configParser) getAllKeys(conf *config) ([]string, error) {
+	if conf == nil {
+		return nil, errConfigMissing
 	}
 
-	return keys, nil
+	return conf.getAllKeys(), nil
 }
 
-func (parser *configParser) parseKeyValue(jsonBody string, variationId string) (string, interface{}, error) {
-	rootNode, err := parser.getEntries(jsonBody)
-	if err != nil {
-		return "", nil, &parseError{"JSON parsing failed. " + err.Error() + "."}
+func (parser *configParser) parseKeyValue(conf *config, variationId string) (string, interface{}, error) {
+	if conf == nil {
+		return "", nil, errConfigMissing
 	}
 
-	for key, value := range rootNode {
-		node := value.(map[string]interface{})
-		if node[settingVariationId].(string) == variationId {
-			return key, node[settingValue], nil
+	for key, setting := range conf.root.Settings {
+		if setting.VariationID == variationId {
+			return key, setting.Value, nil
 		}
 
-		rolloutRules := node[settingRolloutRules].([]interface{})
-		percentageRules := node[settingRolloutPercentageItems].([]interface{})
-
-		for _, rolloutItem := range rolloutRules {
-			rule := rolloutItem.(map[string]interface{})
-			if rule[rolloutVariationId].(string) == variationId {
-				return key, rule[rolloutValue], nil
+		for _, rule := range setting.RolloutRules {
+			if rule.VariationID == variationId {
+				return key, rule.Value, nil
 			}
 		}
 
-		for _, percentageItem := range percentageRules {
-			rule := percentageItem.(map[string]interface{})
-			if rule[percentageItemVariationId].(string) == variationId {
-				return key, rule[percentageItemValue], nil
+		for _, rule := range setting.PercentageOptions {
+			if rule.VariationID == variationId {
+				return key, rule.Value, nil
 			}
 		}
 	}
 
-	return "", nil, &parseError{"JSON parsing failed."}
+	return "", nil, &parseError{"Value not found for variation ID " + variationId + "."}
 }
 
-func (parser *configParser) parseInternal(jsonBody string, key string, user *User) (interface{}, string, error) {
+// parseDetails evaluates the setting identified by key and returns the details of
+// the evaluation along with the type of the setting. On failure the Error field of
+// the returned details is set and its Value is nil.
+func (parser *configParser) parseDetails(conf *config, key string, user *User) (EvaluationDetails, SettingType) {
 	if len(key) == 0 {
 		panic("Key cannot be empty")
 	}
 
-	rootNode, err := parser.getEntries(jsonBody)
-	if err != nil {
-		return nil, "", &parseError{"JSON parsing failed. " + err.Error() + "."}
+	details := EvaluationDetails{Key: key, User: user}
+	if conf == nil {
+		details.Error = errConfigMissing
+		return details, UnknownSetting
 	}
 
-	node := rootNode[key]
-	if node == nil {
-		keys := make([]string, len(rootNode))
-		i := 0
-		for k := range rootNode {
-			keys[i] = k
-			i++
-		}
-
-		return nil, "", &parseError{"Value not found for key " + key +
-			". Here are the available keys: " + strings.Join(keys, ", ")}
+	details.FetchTime = conf.fetchTime
+	setting := conf.root.Settings[key]
+	if setting == nil {
+		details.Error = &parseError{"Value not found for key " + key +
+			". Here are the available keys: " + strings.Join(conf.getAllKeys(), ", ")}
+		return details, UnknownSetting
 	}
 
-	parsed, variationId := parser.evaluator.evaluate(node, key, user)
+	parsed, variationId, matchedRule, matchedPercentageOption := parser.evaluator.evaluate(setting, key, user)
 	if parsed == nil {
-		return nil, "", &parseError{"Null evaluated for key " + key + "."}
+		details.Error = &parseError{"Null evaluated for key " + key + "."}
+		return details, setting.Type
 	}
 
-	return parsed, variationId, nil
-}
-
-func (parser *configParser) getEntries(jsonBody string) (map[string]interface{}, error) {
-	rootNode, err := parser.deserialize(jsonBody)
-	if err != nil {
-		return nil, err
+	details.Value = parsed
+	details.VariationID = variationId
+	if matchedRule != nil {
+		rule := *matchedRule
+		details.MatchedRule = &rule
 	}
-
-	entries, ok := rootNode[entries].(map[string]interface{})
-	if !ok {
-		return nil, &parseError{"JSON mapping failed, json: " + jsonBody}
+	if matchedPercentageOption != nil {
+		option := *matchedPercentageOption
+		details.MatchedPercentageOption = &option
 	}
-
-	return entries, nil
+	return details, setting.Type
 }
 
-func (parser *configParser) deserialize(jsonBody string) (map[string]interface{}, error) {
-	var root interface{}
-	err := json.Unmarshal([]byte(jsonBody), &root)
-	if err != nil {
-		return nil, err
-	}
-
-	rootNode, ok := root.(map[string]interface{})
-	if !ok {
-		return nil, &parseError{"JSON mapping failed, json: " + jsonBody}
+// convertValue converts a value decoded from the configuration JSON
+// to the Go type belonging to the given setting kind.
+func convertValue(value interface{}, kind SettingType) (interface{}, bool) {
+	switch kind {
+	case BoolSetting:
+		v, ok := value.(bool)
+		return v, ok
+	case StringSetting:
+		v, ok := value.(string)
+		return v, ok
+	case IntSetting:
+		v, ok := value.(float64)
+		if !ok || v != math.Trunc(v) {
+			return nil, false
+		}
+		return int(v), true
+	case FloatSetting:
+		v, ok := value.(float64)
+		return v, ok
 	}
-
-	return rootNode, nil
+	return nil, false
 }
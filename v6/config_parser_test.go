@@ -1,14 +1,17 @@
 package configcat
 
 import (
+	"fmt"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestConfigParser_Parse(t *testing.T) {
 	jsonBody := "{ \"f\": { \"keyDouble\": { \"v\": 120.121238476, \"p\": [], \"r\": [], \"i\":\"\" }}}"
 	parser := newParser(DefaultLogger(LogLevelWarn))
 
-	val, err := parser.parse(jsonBody, "keyDouble", nil)
+	val, err := parser.parse(mustParseConfig(t, jsonBody), "keyDouble", nil)
 
 	if err != nil || val != 120.121238476 {
 		t.Error("Expecting 120.121238476 as interface")
@@ -17,9 +20,8 @@ func TestConfigParser_Parse(t *testing.T) {
 
 func TestConfigParser_BadJson(t *testing.T) {
 	jsonBody := ""
-	parser := newParser(DefaultLogger(LogLevelWarn))
 
-	_, err := parser.parse(jsonBody, "keyDouble", nil)
+	_, err := parseConfig(jsonBody, time.Time{})
 
 	if err == nil {
 		t.Error("Expecting JSON error")
@@ -28,14 +30,13 @@ func TestConfigParser_BadJson(t *testing.T) {
 	t.Log(err.Error())
 }
 
-func TestConfigParser_BadJson_String(t *testing.T) {
-	jsonBody := ""
+func TestConfigParser_MissingConfig(t *testing.T) {
 	parser := newParser(DefaultLogger(LogLevelWarn))
 
-	_, err := parser.parse(jsonBody, "key", nil)
+	_, err := parser.parse(nil, "key", nil)
 
 	if err == nil {
-		t.Error("Expecting JSON error")
+		t.Error("Expecting missing config error")
 	}
 
 	t.Log(err.Error())
@@ -45,7 +46,7 @@ func TestConfigParser_WrongKey(t *testing.T) {
 	jsonBody := "{ \"keyDouble\": { \"Value\": 120.121238476, \"SettingType\": 0, \"RolloutPercentageItems\": [], \"RolloutRules\": [] }}"
 	parser := newParser(DefaultLogger(LogLevelWarn))
 
-	_, err := parser.parse(jsonBody, "wrongKey", nil)
+	_, err := parser.parse(mustParseConfig(t, jsonBody), "wrongKey", nil)
 
 	if err == nil {
 		t.Error("Expecting key not found error")
@@ -54,11 +55,30 @@ func TestConfigParser_WrongKey(t *testing.T) {
 	t.Log(err.Error())
 }
 
+func TestConfigParser_NullEntries(t *testing.T) {
+	jsonBody := `{"f": {"null": null, "key": {"v": "default", "i": "d", "r": [null, {"a": "Email", "t": 2, "c": "@example.com", "v": "rule", "i": "r"}], "p": [null]}}}`
+	parser := newParser(DefaultLogger(LogLevelWarn))
+	conf := mustParseConfig(t, jsonBody)
+
+	if val, err := parser.parse(conf, "key", NewUser("id")); err != nil || val != "default" {
+		t.Errorf("Expecting the default value, got %v, %v", val, err)
+	}
+	if val, err := parser.parse(conf, "key", NewUserWithAdditionalAttributes("id", "a@example.com", "", nil)); err != nil || val != "rule" {
+		t.Errorf("Expecting the value of the targeting rule, got %v, %v", val, err)
+	}
+	if _, err := parser.parse(conf, "null", nil); err == nil {
+		t.Error("Expecting key not found error")
+	}
+	if key, val, err := parser.parseKeyValue(conf, "r"); err != nil || key != "key" || val != "rule" {
+		t.Errorf("Expecting the value of the targeting rule, got %v, %v, %v", key, val, err)
+	}
+}
+
 func TestConfigParser_EmptyNode(t *testing.T) {
 	jsonBody := "{ \"keyDouble\": { }}"
 	parser := newParser(DefaultLogger(LogLevelWarn))
 
-	_, err := parser.parse(jsonBody, "keyDouble", nil)
+	_, err := parser.parse(mustParseConfig(t, jsonBody), "keyDouble", nil)
 
 	if err == nil {
 		t.Error("Expecting invalid JSON error")
@@ -66,3 +86,51 @@ func TestConfigParser_EmptyNode(t *testing.T) {
 
 	t.Log(err.Error())
 }
+
+func TestConfigParser_ParseTyped_Int(t *testing.T) {
+	jsonBody := "{ \"f\": { \"keyInt\": { \"v\": 12, \"t\": 2, \"p\": [], \"r\": [], \"i\":\"\" }}}"
+	parser := newParser(DefaultLogger(LogLevelWarn))
+
+	val, err := parser.parseTyped(mustParseConfig(t, jsonBody), "keyInt", IntSetting, nil)
+
+	if err != nil || val != 12 {
+		t.Error("Expecting 12 as int")
+	}
+}
+
+func TestConfigParser_ParseTyped_Mismatch(t *testing.T) {
+	jsonBody := "{ \"f\": { \"keyInt\": { \"v\": 12, \"t\": 2, \"p\": [], \"r\": [], \"i\":\"\" }}}"
+	parser := newParser(DefaultLogger(LogLevelWarn))
+
+	_, err := parser.parseTyped(mustParseConfig(t, jsonBody), "keyInt", FloatSetting, nil)
+
+	if err == nil {
+		t.Error("Expecting type mismatch error")
+	}
+
+	t.Log(err.Error())
+}
+
+func mustParseConfig(t testing.TB, jsonBody string) *config {
+	conf, err := parseConfig(jsonBody, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conf
+}
+
+// testConfigJson returns a configuration JSON with a single "key" setting holding value.
+func testConfigJson(value string) string {
+	return fmt.Sprintf(jsonFormat, "key", strconv.Quote(value))
+}
+
+// configValue returns the value of the "key" setting of a configuration
+// returned by a refresh policy, or an empty string if there is no such setting.
+func configValue(result interface{}) string {
+	conf, _ := result.(*config)
+	if conf == nil || conf.root.Settings["key"] == nil {
+		return ""
+	}
+	value, _ := conf.root.Settings["key"].Value.(string)
+	return value
+}
@@ -0,0 +1,91 @@
+package configcat
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_UnmarshalJSON(t *testing.T) {
+	var conf Config
+	if err := json.Unmarshal([]byte(detailsJson), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	setting := conf.Settings["key"]
+	expected := &Setting{
+		Value: "value",
+		Type:  StringSetting,
+		RolloutRules: []*RolloutRule{{
+			Value:               "ruleValue",
+			ComparisonAttribute: "Email",
+			Comparator:          Contains,
+			ComparisonValue:     "@example.com",
+			VariationID:         "ruleId",
+		}},
+		PercentageOptions: []*PercentageOption{{Value: "percentageValue", Percentage: 100, VariationID: "percentageId"}},
+		VariationID:       "valueId",
+	}
+	if !reflect.DeepEqual(setting, expected) {
+		t.Errorf("Unexpected setting %+v", setting)
+	}
+}
+
+func TestConfig_MarshalJSON(t *testing.T) {
+	builder := NewConfigBuilder().Redirect("https://cdn-eu.configcat.com", ForceRedirect)
+	builder.Setting("key", 1).WithVariationID("one").
+		Rule("Version", GreaterSemver, "1.0.0", 2).WithVariationID("two").
+		Percentage(100, 3).WithVariationID("three")
+	conf, err := builder.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonBody, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed Config
+	if err := json.Unmarshal(jsonBody, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&parsed, conf) {
+		t.Errorf("Expecting the configuration to survive a round trip, got %s", jsonBody)
+	}
+}
+
+func TestConfig_RoundTripWithoutSettingType(t *testing.T) {
+	jsonBody := `{"f":{"key":{"v":"value","p":[],"r":[],"i":"id"}}}`
+	var conf Config
+	if err := json.Unmarshal([]byte(jsonBody), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Settings["key"].Type != UnknownSetting {
+		t.Errorf("Expecting the unknown setting type, got %v", conf.Settings["key"].Type)
+	}
+
+	marshalled, err := json.Marshal(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(marshalled) != jsonBody {
+		t.Errorf("Expecting the configuration to survive a round trip, got %s", marshalled)
+	}
+}
+
+func TestComparator_String(t *testing.T) {
+	tests := map[Comparator]string{
+		OneOf:              "IS ONE OF",
+		NotContains:        "DOES NOT CONTAIN",
+		LessEqualSemver:    "<= (SemVer)",
+		GreaterEqualNumber: ">= (Number)",
+		NotOneOfSensitive:  "IS NOT ONE OF (Sensitive)",
+		Comparator(18):     "unknown",
+		Comparator(-1):     "unknown",
+	}
+	for comparator, expected := range tests {
+		if text := comparator.String(); text != expected {
+			t.Errorf("Expecting %q for %d, got %q", expected, int(comparator), text)
+		}
+	}
+}
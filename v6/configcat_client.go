@@ -2,6 +2,8 @@
 package configcat
 
 import (
+	"context"
+	"math/rand"
 	"net/http"
 	"time"
 )
@@ -12,6 +14,10 @@ type Client struct {
 	refreshPolicy           refreshPolicy
 	maxWaitTimeForSyncCalls time.Duration
 	logger                  Logger
+	changes                 *changeBroadcaster
+	overrides               *localOverrides
+	hooks                   evaluationHooks
+	clock                   Clock
 }
 
 // ClientConfig describes custom configuration options for the Client.
@@ -20,6 +26,9 @@ type ClientConfig struct {
 	Logger Logger
 	// The custom cache implementation used to store the configuration.
 	Cache ConfigCache
+	// The custom context-aware cache implementation used to store the configuration.
+	// When it's set, it's used in place of Cache.
+	CacheCtx ConfigCacheCtx
 	// The maximum time how long at most the synchronous calls (e.g. client.get(...)) should block the caller.
 	// If it's 0 then the caller will be blocked in case of sync calls, until the operation succeeds or fails.
 	MaxWaitTimeForSyncCalls time.Duration
@@ -34,13 +43,35 @@ type ClientConfig struct {
 	// Default: Global. Set this parameter to be in sync with the Data Governance preference on the Dashboard:
 	// https://app.configcat.com/organization/data-governance (Only Organization Admins have access)
 	DataGovernance DataGovernance
+	// The listener called when a refresh brings a configuration with changed settings, in any refresh mode.
+	// It gets the configuration before and after the change, and the keys of the changed settings.
+	// More listeners can be added with Client.Subscribe.
+	ChangeListener func(change *ConfigChange)
+	// The local feature flag and setting overrides, e.g. for local development and testing.
+	// When the behavior is LocalOnly, the configuration is never fetched from ConfigCat.
+	FlagOverrides *FlagOverrides
+	// Offline indicates whether the client starts in offline mode, in which no HTTP requests
+	// are made and the configuration is served from the cache. See Client.SetOnline.
+	Offline bool
+	// Backoff describes how the polls and lazy loads are delayed after failed fetches.
+	// When it's nil, the fetches are made regardless of the earlier failures.
+	Backoff *Backoff
+	// The source of randomness of the poll and backoff jitters.
+	// Default: a source seeded with the current time. Set it to make the jitters deterministic in tests.
+	RandSource rand.Source
+	// The clock measuring the ages of the cached configurations, the poll intervals, the fetch timeouts
+	// and the MaxWaitTimeForSyncCalls.
+	// Default: the wall time. Set it, e.g. to a ManualClock, to drive the time manually in tests.
+	Clock Clock
+	// Hooks holds the hooks called around every setting evaluation, in order.
+	Hooks []EvaluationHook
 }
 
 func defaultConfig() ClientConfig {
 	return ClientConfig{
 		Logger:                  DefaultLogger(LogLevelWarn),
 		BaseUrl:                 "",
-		Cache:                   newInMemoryConfigCache(),
+		CacheCtx:                NewInMemoryCache(),
 		MaxWaitTimeForSyncCalls: 0,
 		HttpTimeout:             time.Second * 15,
 		Transport:               http.DefaultTransport,
@@ -70,8 +101,12 @@ func newInternal(sdkKey string, config ClientConfig, fetcher configProvider) *Cl
 		config.Logger = defaultConfig.Logger
 	}
 
-	if config.Cache == nil {
-		config.Cache = defaultConfig.Cache
+	if config.CacheCtx == nil {
+		if config.Cache != nil {
+			config.CacheCtx = contextCache{config.Cache}
+		} else {
+			config.CacheCtx = defaultConfig.CacheCtx
+		}
 	}
 
 	if config.MaxWaitTimeForSyncCalls < 0 {
@@ -92,15 +127,42 @@ func newInternal(sdkKey string, config ClientConfig, fetcher configProvider) *Cl
 
 	parser := newParser(config.Logger)
 
+	var overrides *localOverrides
+	if config.FlagOverrides != nil {
+		overrides = newLocalOverrides(config.FlagOverrides, config.Logger)
+		if config.FlagOverrides.Behavior == LocalOnly {
+			fetcher = localOnlyProvider{}
+		}
+	}
+
 	if fetcher == nil {
 		fetcher = newConfigFetcher(sdkKey, config, parser)
 	}
 
-	return &Client{
+	client := &Client{
 		parser:                  parser,
-		refreshPolicy:           config.Mode.accept(newRefreshPolicyFactory(fetcher, config.Cache, config.Logger, sdkKey)),
 		maxWaitTimeForSyncCalls: config.MaxWaitTimeForSyncCalls,
-		logger:                  config.Logger}
+		logger:                  config.Logger,
+		changes:                 newChangeBroadcaster(config.Logger),
+		overrides:               overrides,
+		hooks:                   config.Hooks,
+		clock:                   config.Clock}
+	if client.clock == nil {
+		client.clock = realClock{}
+	}
+	if config.ChangeListener != nil {
+		client.changes.subscribe(config.ChangeListener)
+	}
+	factory := newRefreshPolicyFactory(fetcher, config.CacheCtx, config.Logger, sdkKey, client.configChanged)
+	factory.offline = config.Offline
+	factory.backoff = config.Backoff
+	factory.random = newLockedRand(config.RandSource)
+	factory.clock = config.Clock
+	client.refreshPolicy = config.Mode.accept(factory)
+	if overrides != nil && len(config.FlagOverrides.FilePath) > 0 && config.FlagOverrides.ReloadInterval > 0 {
+		overrides.watch(config.FlagOverrides.ReloadInterval, client.overridesChanged)
+	}
+	return client
 }
 
 // GetValue returns a value synchronously as interface{} from the configuration identified by the given key.
@@ -116,22 +178,20 @@ func (client *Client) GetValueAsync(key string, defaultValue interface{}, comple
 // GetValueForUser returns a value synchronously as interface{} from the configuration identified by the given key.
 // Optional user argument can be passed to identify the caller.
 func (client *Client) GetValueForUser(key string, defaultValue interface{}, user *User) interface{} {
+	return client.GetValueCtx(context.Background(), key, defaultValue, user)
+}
+
+// GetValueCtx is like GetValueForUser but stops waiting for the configuration when ctx is done,
+// in which case the value is evaluated against the last cached configuration.
+// A fetch started by the lazy loading mode isn't aborted, since it's shared with the
+// concurrent getter calls; it completes in the background. RefreshCtx aborts the fetch.
+func (client *Client) GetValueCtx(ctx context.Context, key string, defaultValue interface{}, user *User) interface{} {
 	if len(key) == 0 {
 		panic("key cannot be empty")
 	}
 
-	if client.maxWaitTimeForSyncCalls > 0 {
-		json, err := client.refreshPolicy.getConfigurationAsync().getOrTimeout(client.maxWaitTimeForSyncCalls)
-		if err != nil {
-			client.logger.Errorf("Policy could not provide the configuration: %s", err.Error())
-			return client.parseJson(client.refreshPolicy.getLastCachedConfig(), key, defaultValue, user)
-		}
-
-		return client.parseJson(json.(string), key, defaultValue, user)
-	}
-
-	json, _ := client.refreshPolicy.getConfigurationAsync().get().(string)
-	return client.parseJson(json, key, defaultValue, user)
+	conf, _ := client.getConfig(ctx)
+	return client.parseJson(ctx, conf, key, defaultValue, user)
 }
 
 // GetValueAsyncForUser reads and sends a value asynchronously to a callback function as interface{} from the configuration identified by the given key.
@@ -142,10 +202,86 @@ func (client *Client) GetValueAsyncForUser(key string, defaultValue interface{},
 	}
 
 	client.refreshPolicy.getConfigurationAsync().accept(func(res interface{}) {
-		completion(client.parseJson(res.(string), key, defaultValue, user))
+		completion(client.parseJson(context.Background(), client.asConfig(res), key, defaultValue, user))
 	})
 }
 
+// GetValueDetails returns the value of the setting identified by the given key along with
+// the details of the evaluation, such as the matched targeting rule or percentage option.
+// Optional user argument can be passed to identify the caller.
+func (client *Client) GetValueDetails(key string, defaultValue interface{}, user *User) EvaluationDetails {
+	if len(key) == 0 {
+		panic("key cannot be empty")
+	}
+
+	conf, _ := client.getConfig(context.Background())
+	return client.getValueDetails(context.Background(), conf, key, defaultValue, user)
+}
+
+// GetBoolValue returns the value of a boolean setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a boolean.
+func (client *Client) GetBoolValue(key string, defaultValue bool) bool {
+	return client.GetBoolValueForUser(key, defaultValue, nil)
+}
+
+// GetBoolValueForUser returns the value of a boolean setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a boolean.
+// Optional user argument can be passed to identify the caller.
+func (client *Client) GetBoolValueForUser(key string, defaultValue bool, user *User) bool {
+	if value, ok := client.getTypedValue("GetBoolValue", key, BoolSetting, defaultValue, user).(bool); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetIntValue returns the value of a whole number setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a whole number.
+func (client *Client) GetIntValue(key string, defaultValue int) int {
+	return client.GetIntValueForUser(key, defaultValue, nil)
+}
+
+// GetIntValueForUser returns the value of a whole number setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a whole number.
+// Optional user argument can be passed to identify the caller.
+func (client *Client) GetIntValueForUser(key string, defaultValue int, user *User) int {
+	if value, ok := client.getTypedValue("GetIntValue", key, IntSetting, defaultValue, user).(int); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetFloatValue returns the value of a decimal number setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a decimal number.
+func (client *Client) GetFloatValue(key string, defaultValue float64) float64 {
+	return client.GetFloatValueForUser(key, defaultValue, nil)
+}
+
+// GetFloatValueForUser returns the value of a decimal number setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a decimal number.
+// Optional user argument can be passed to identify the caller.
+func (client *Client) GetFloatValueForUser(key string, defaultValue float64, user *User) float64 {
+	if value, ok := client.getTypedValue("GetFloatValue", key, FloatSetting, defaultValue, user).(float64); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetStringValue returns the value of a text setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a text.
+func (client *Client) GetStringValue(key string, defaultValue string) string {
+	return client.GetStringValueForUser(key, defaultValue, nil)
+}
+
+// GetStringValueForUser returns the value of a text setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a text.
+// Optional user argument can be passed to identify the caller.
+func (client *Client) GetStringValueForUser(key string, defaultValue string, user *User) string {
+	if value, ok := client.getTypedValue("GetStringValue", key, StringSetting, defaultValue, user).(string); ok {
+		return value
+	}
+	return defaultValue
+}
+
 // GetVariationId returns a Variation ID synchronously as string from the configuration identified by the given key.
 func (client *Client) GetVariationId(key string, defaultVariationId string) string {
 	return client.GetVariationIdForUser(key, defaultVariationId, nil)
@@ -159,22 +295,20 @@ func (client *Client) GetVariationIdAsync(key string, defaultVariationId string,
 // GetVariationIdForUser returns a Variation ID synchronously as string from the configuration identified by the given key.
 // Optional user argument can be passed to identify the caller.
 func (client *Client) GetVariationIdForUser(key string, defaultVariationId string, user *User) string {
+	return client.GetVariationIdCtx(context.Background(), key, defaultVariationId, user)
+}
+
+// GetVariationIdCtx is like GetVariationIdForUser but stops waiting for the configuration when ctx is done,
+// in which case the Variation ID is evaluated against the last cached configuration.
+// A fetch started by the lazy loading mode isn't aborted, since it's shared with the
+// concurrent getter calls; it completes in the background. RefreshCtx aborts the fetch.
+func (client *Client) GetVariationIdCtx(ctx context.Context, key string, defaultVariationId string, user *User) string {
 	if len(key) == 0 {
 		panic("key cannot be empty")
 	}
 
-	if client.maxWaitTimeForSyncCalls > 0 {
-		json, err := client.refreshPolicy.getConfigurationAsync().getOrTimeout(client.maxWaitTimeForSyncCalls)
-		if err != nil {
-			client.logger.Errorf("Policy could not provide the configuration: %s", err.Error())
-			return client.parseVariationId(client.refreshPolicy.getLastCachedConfig(), key, defaultVariationId, user)
-		}
-
-		return client.parseVariationId(json.(string), key, defaultVariationId, user)
-	}
-
-	json, _ := client.refreshPolicy.getConfigurationAsync().get().(string)
-	return client.parseVariationId(json, key, defaultVariationId, user)
+	conf, _ := client.getConfig(ctx)
+	return client.parseVariationId(ctx, conf, key, defaultVariationId, user)
 }
 
 // GetVariationIdAsyncForUser reads and sends a Variation Id asynchronously to a callback function as string from the configuration identified by the given key.
@@ -185,7 +319,7 @@ func (client *Client) GetVariationIdAsyncForUser(key string, defaultVariationId
 	}
 
 	client.refreshPolicy.getConfigurationAsync().accept(func(res interface{}) {
-		completion(client.parseVariationId(res.(string), key, defaultVariationId, user))
+		completion(client.parseVariationId(context.Background(), client.asConfig(res), key, defaultVariationId, user))
 	})
 }
 
@@ -202,124 +336,320 @@ func (client *Client) GetAllVariationIdsAsync(completion func(result []string, e
 // GetAllVariationIdsForUser returns the Variation IDs synchronously as []string from the configuration.
 // Optional user argument can be passed to identify the caller.
 func (client *Client) GetAllVariationIdsForUser(user *User) ([]string, error) {
-	if client.maxWaitTimeForSyncCalls > 0 {
-		json, err := client.refreshPolicy.getConfigurationAsync().getOrTimeout(client.maxWaitTimeForSyncCalls)
-		if err != nil {
-			client.logger.Errorf("Policy could not provide the configuration: %s", err.Error())
-			return nil, err
-		}
-
-		return client.getVariationIds(json.(string), user)
+	conf, err := client.getConfig(context.Background())
+	if err != nil {
+		return nil, err
 	}
 
-	json, _ := client.refreshPolicy.getConfigurationAsync().get().(string)
-	return client.getVariationIds(json, user)
+	return client.getVariationIds(conf, user)
 }
 
 // GetAllVariationIdsAsyncForUser reads and sends a Variation ID asynchronously to a callback function as []string from the configuration.
 // Optional user argument can be passed to identify the caller.
 func (client *Client) GetAllVariationIdsAsyncForUser(user *User, completion func(result []string, err error)) {
 	client.refreshPolicy.getConfigurationAsync().accept(func(res interface{}) {
-		completion(client.getVariationIds(res.(string), user))
+		completion(client.getVariationIds(client.asConfig(res), user))
 	})
 }
 
-// GetKeyAndValue returns the key of a setting and its value identified by the given Variation ID.
-func (client *Client) GetKeyAndValue(variationId string) (string, interface{}) {
-	if client.maxWaitTimeForSyncCalls > 0 {
-		json, err := client.refreshPolicy.getConfigurationAsync().getOrTimeout(client.maxWaitTimeForSyncCalls)
-		if err != nil {
-			client.logger.Errorf("Policy could not provide the configuration: %s", err.Error())
-			return "", nil
+// GetAllValues evaluates all the settings and returns their values keyed by the setting keys.
+func (client *Client) GetAllValues() map[string]interface{} {
+	return client.GetAllValuesForUser(nil)
+}
+
+// GetAllValuesForUser evaluates all the settings against the same configuration
+// and returns their values keyed by the setting keys.
+// Optional user argument can be passed to identify the caller.
+func (client *Client) GetAllValuesForUser(user *User) map[string]interface{} {
+	conf, _ := client.getConfig(context.Background())
+	allDetails := client.getAllValueDetails(conf, user)
+	values := make(map[string]interface{}, len(allDetails))
+	for key, details := range allDetails {
+		if details.Error == nil {
+			values[key] = details.Value
 		}
+	}
+
+	return values
+}
 
-		return client.getKeyAndValue(json.(string), variationId)
+// GetAllValueDetails evaluates all the settings against the same configuration
+// and returns the details of the evaluations keyed by the setting keys.
+// Optional user argument can be passed to identify the caller.
+func (client *Client) GetAllValueDetails(user *User) map[string]EvaluationDetails {
+	conf, _ := client.getConfig(context.Background())
+	return client.getAllValueDetails(conf, user)
+}
+
+// GetKeyAndValue returns the key of a setting and its value identified by the given Variation ID.
+func (client *Client) GetKeyAndValue(variationId string) (string, interface{}) {
+	conf, err := client.getConfig(context.Background())
+	if err != nil {
+		return "", nil
 	}
 
-	json, _ := client.refreshPolicy.getConfigurationAsync().get().(string)
-	return client.getKeyAndValue(json, variationId)
+	return client.getKeyAndValue(conf, variationId)
 }
 
 // GetAllVariationIdsAsyncForUser reads and sends the key of a setting and its value identified by the given
 // Variation ID asynchronously to a callback function as (string, interface{}) from the configuration.
 func (client *Client) GetKeyAndValueAsync(variationId string, completion func(key string, value interface{})) {
 	client.refreshPolicy.getConfigurationAsync().accept(func(res interface{}) {
-		completion(client.getKeyAndValue(res.(string), variationId))
+		completion(client.getKeyAndValue(client.asConfig(res), variationId))
 	})
 }
 
 // GetAllKeys retrieves all the setting keys.
 func (client *Client) GetAllKeys() ([]string, error) {
-	if client.maxWaitTimeForSyncCalls > 0 {
-		json, err := client.refreshPolicy.getConfigurationAsync().getOrTimeout(client.maxWaitTimeForSyncCalls)
-		if err != nil {
-			client.logger.Errorf("Policy could not provide the configuration: %s", err.Error())
-			return nil, err
-		}
+	return client.GetAllKeysCtx(context.Background())
+}
 
-		return client.parser.getAllKeys(json.(string))
+// GetAllKeysCtx is like GetAllKeys but stops waiting for the configuration when ctx is done,
+// in which case the error of ctx is returned.
+func (client *Client) GetAllKeysCtx(ctx context.Context) ([]string, error) {
+	conf, err := client.getConfig(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	json, _ := client.refreshPolicy.getConfigurationAsync().get().(string)
-	return client.parser.getAllKeys(json)
+	return client.parser.getAllKeys(conf)
 }
 
 // GetAllKeysAsync retrieves all the setting keys asynchronously.
 func (client *Client) GetAllKeysAsync(completion func(result []string, err error)) {
 	client.refreshPolicy.getConfigurationAsync().accept(func(res interface{}) {
-		completion(client.parser.getAllKeys(res.(string)))
+		completion(client.parser.getAllKeys(client.asConfig(res)))
 	})
 }
 
+// Snapshot returns an immutable view of the current configuration. All the evaluations made
+// on the snapshot use the same configuration version, even when it's refreshed in the meantime.
+func (client *Client) Snapshot() *Snapshot {
+	return client.SnapshotCtx(context.Background())
+}
+
+// SnapshotCtx is like Snapshot but stops waiting for the configuration when ctx is done,
+// in which case the snapshot holds the last cached configuration.
+func (client *Client) SnapshotCtx(ctx context.Context) *Snapshot {
+	conf, _ := client.getConfig(ctx)
+	return newSnapshot(client, conf)
+}
+
 // Refresh initiates a force refresh synchronously on the cached configuration.
+// RefreshCtx reports the outcome of the refresh.
 func (client *Client) Refresh() {
-	if client.maxWaitTimeForSyncCalls > 0 {
-		client.refreshPolicy.refreshAsync().waitOrTimeout(client.maxWaitTimeForSyncCalls)
-	} else {
-		client.refreshPolicy.refreshAsync().wait()
+	client.RefreshCtx(context.Background())
+}
+
+// RefreshCtx initiates a force refresh synchronously on the cached configuration, aborting
+// the HTTP request of the refresh when ctx is done. It returns whether a new configuration
+// was fetched, the configuration was not modified or the fetch failed. On failure the returned
+// error is a *FetchError holding the HTTP status code and the underlying error, or wrapping
+// the error of ctx when it's done. In offline mode nothing is fetched and NotModified is returned.
+func (client *Client) RefreshCtx(ctx context.Context) (FetchStatus, error) {
+	refresh := client.refreshPolicy.refreshAsync(ctx)
+	ctx, cancel := client.syncContext(ctx)
+	defer cancel()
+	result, err := refresh.getCtx(ctx)
+	if err != nil {
+		return Failure, &FetchError{Err: err}
 	}
+
+	response := result.(fetchResponse)
+	return response.status, response.fetchErr()
 }
 
 // RefreshAsync initiates a force refresh asynchronously on the cached configuration.
 func (client *Client) RefreshAsync(completion func()) {
-	client.refreshPolicy.refreshAsync().accept(completion)
+	client.RefreshAsyncWithStatus(func(status FetchStatus, err error) {
+		completion()
+	})
+}
+
+// RefreshAsyncWithStatus initiates a force refresh asynchronously on the cached configuration.
+// The completion gets the outcome of the refresh as described at RefreshCtx.
+func (client *Client) RefreshAsyncWithStatus(completion func(status FetchStatus, err error)) {
+	client.refreshPolicy.refreshAsync(context.Background()).accept(func(result interface{}) {
+		response := result.(fetchResponse)
+		completion(response.status, response.fetchErr())
+	})
+}
+
+// SetOffline switches the client to offline mode. While offline no HTTP requests are made,
+// the polling is paused and the values are evaluated against the cached configuration.
+// The refreshes do nothing and report NotModified.
+func (client *Client) SetOffline() {
+	client.refreshPolicy.setOffline(true)
+	client.logger.Infof("Switched to offline mode.")
+}
+
+// SetOnline switches the client back to online mode, resuming the fetching of the configuration.
+func (client *Client) SetOnline() {
+	client.refreshPolicy.setOffline(false)
+	client.logger.Infof("Switched to online mode.")
+}
+
+// IsOffline reports whether the client is in offline mode.
+func (client *Client) IsOffline() bool {
+	return client.refreshPolicy.isOffline()
+}
+
+// Subscribe registers a listener called when a refresh brings a configuration with changed settings.
+// The listeners are called one after the other on a dedicated goroutine, in the order of the changes.
+// The changes made while the listeners are busy are merged into a single change from the
+// last delivered configuration to the latest one, so a slow listener may not see every version.
+// The returned function unsubscribes the listener.
+func (client *Client) Subscribe(listener func(change *ConfigChange)) (unsubscribe func()) {
+	return client.changes.subscribe(listener)
 }
 
 // Close shuts down the client, after closing, it shouldn't be used
 func (client *Client) Close() {
 	client.refreshPolicy.close()
+	if client.overrides != nil {
+		client.overrides.close()
+	}
+	client.changes.close()
+}
+
+// configChanged notifies the change listeners about a configuration with new content.
+func (client *Client) configChanged(oldConfig, newConfig *config) {
+	client.notifyChange(client.applyOverrides(oldConfig), client.applyOverrides(newConfig))
+}
+
+// overridesChanged notifies the change listeners about reloaded flag overrides.
+func (client *Client) overridesChanged(oldLocal, newLocal *config) {
+	remote := client.refreshPolicy.getLastCachedConfig()
+	client.notifyChange(client.overrides.merge(remote, oldLocal), client.overrides.apply(remote))
 }
 
-func (client *Client) parseJson(json string, key string, defaultValue interface{}, user *User) interface{} {
-	parsed, err := client.parser.parse(json, key, user)
+// notifyChange notifies the change listeners about the change of the configuration evaluated against.
+// Nothing is published when no setting changed, e.g. when only the preferences changed,
+// or a remote change is hidden by a local override.
+func (client *Client) notifyChange(oldConfig, newConfig *config) {
+	if !client.changes.hasListeners() {
+		return
+	}
+
+	added, removed, modified := diffConfigs(oldConfig, newConfig)
+	if len(added) == 0 && len(removed) == 0 && len(modified) == 0 {
+		return
+	}
+	client.changes.publish(&ConfigChange{
+		Old:      newSnapshot(client, oldConfig),
+		New:      newSnapshot(client, newConfig),
+		Added:    added,
+		Removed:  removed,
+		Modified: modified,
+	})
+}
+
+// getConfig returns the current configuration, blocking until ctx is done or at most
+// maxWaitTimeForSyncCalls when it's set. When the waiting is interrupted,
+// the last cached configuration is returned along with the reason.
+func (client *Client) getConfig(ctx context.Context) (*config, error) {
+	ctx, cancel := client.syncContext(ctx)
+	defer cancel()
+
+	conf, err := client.refreshPolicy.getConfigurationAsync().getCtx(ctx)
 	if err != nil {
+		client.logger.Errorf("Policy could not provide the configuration: %s", err.Error())
+		return client.applyOverrides(client.refreshPolicy.getLastCachedConfig()), err
+	}
+
+	return client.asConfig(conf), nil
+}
+
+// syncContext returns a context for the synchronous calls which is
+// bounded by maxWaitTimeForSyncCalls, measured with the clock, when it's set.
+func (client *Client) syncContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if client.maxWaitTimeForSyncCalls > 0 {
+		return withClockTimeout(ctx, client.clock, client.maxWaitTimeForSyncCalls)
+	}
+	return context.WithCancel(ctx)
+}
+
+// asConfig converts the result of a refresh policy to the configuration to evaluate against.
+func (client *Client) asConfig(result interface{}) *config {
+	conf, _ := result.(*config)
+	return client.applyOverrides(conf)
+}
+
+// applyOverrides combines the configuration with the local flag overrides when they're set.
+func (client *Client) applyOverrides(conf *config) *config {
+	if client.overrides == nil {
+		return conf
+	}
+	return client.overrides.apply(conf)
+}
+
+func (client *Client) getTypedValue(method string, key string, kind SettingType, defaultValue interface{}, user *User) interface{} {
+	if len(key) == 0 {
+		panic("key cannot be empty")
+	}
+
+	conf, _ := client.getConfig(context.Background())
+	return client.parseTyped(context.Background(), conf, method, key, kind, defaultValue, user)
+}
+
+func (client *Client) parseTyped(ctx context.Context, conf *config, method string, key string, kind SettingType, defaultValue interface{}, user *User) interface{} {
+	client.hooks.before(ctx, key, user, defaultValue)
+	details := client.parser.parseTypedDetails(conf, key, kind, user)
+	if details.Error != nil {
+		client.logger.Errorf(
+			"Evaluating %s(%s) failed. Returning defaultValue: [%v]. %s.",
+			method,
+			key,
+			defaultValue,
+			details.Error.Error())
+		details.Value = defaultValue
+		details.IsDefaultValue = true
+	}
+	client.hooks.after(ctx, details, defaultValue)
+
+	return details.Value
+}
+
+func (client *Client) parseJson(ctx context.Context, conf *config, key string, defaultValue interface{}, user *User) interface{} {
+	return client.getValueDetails(ctx, conf, key, defaultValue, user).Value
+}
+
+func (client *Client) getValueDetails(ctx context.Context, conf *config, key string, defaultValue interface{}, user *User) EvaluationDetails {
+	client.hooks.before(ctx, key, user, defaultValue)
+	details, _ := client.parser.parseDetails(conf, key, user)
+	if details.Error != nil {
 		client.logger.Errorf(
 			"Evaluating GetValue(%s) failed. Returning defaultValue: [%v]. %s.",
 			key,
 			defaultValue,
-			err.Error())
-		return defaultValue
+			details.Error.Error())
+		details.Value = defaultValue
+		details.IsDefaultValue = true
 	}
+	client.hooks.after(ctx, details, defaultValue)
 
-	return parsed
+	return details
 }
 
-func (client *Client) parseVariationId(json string, key string, defaultVariationId string, user *User) string {
-	parsed, err := client.parser.parseVariationId(json, key, user)
-	if err != nil {
+func (client *Client) parseVariationId(ctx context.Context, conf *config, key string, defaultVariationId string, user *User) string {
+	client.hooks.before(ctx, key, user, defaultVariationId)
+	details, _ := client.parser.parseDetails(conf, key, user)
+	if details.Error != nil {
 		client.logger.Errorf(
 			"Evaluating GetVariationId(%s) failed. Returning defaultVariationId: [%v]. %s.",
 			key,
 			defaultVariationId,
-			err.Error())
-		return defaultVariationId
+			details.Error.Error())
+		details.VariationID = defaultVariationId
+		details.IsDefaultValue = true
 	}
+	client.hooks.after(ctx, details, defaultVariationId)
 
-	return parsed
+	return details.VariationID
 }
 
-func (client *Client) getVariationIds(json string, user *User) ([]string, error) {
-	keys, err := client.parser.getAllKeys(json)
+func (client *Client) getVariationIds(conf *config, user *User) ([]string, error) {
+	keys, err := client.parser.getAllKeys(conf)
 	if err != nil {
 		client.logger.Errorf(
 			"Evaluating GetAllVariationIds() failed. Returning nil. %s.",
@@ -328,14 +658,30 @@ func (client *Client) getVariationIds(json string, user *User) ([]string, error)
 	}
 	variationIds := make([]string, len(keys))
 	for index, value := range keys {
-		variationIds[index] = client.parseVariationId(json, value, "", user)
+		variationIds[index] = client.parseVariationId(context.Background(), conf, value, "", user)
 	}
 
 	return variationIds, nil
 }
 
-func (client *Client) getKeyAndValue(json string, variationId string) (string, interface{}) {
-	key, value, err := client.parser.parseKeyValue(json, variationId)
+func (client *Client) getAllValueDetails(conf *config, user *User) map[string]EvaluationDetails {
+	keys, err := client.parser.getAllKeys(conf)
+	if err != nil {
+		client.logger.Errorf(
+			"Evaluating GetAllValues() failed. Returning empty result. %s.",
+			err.Error())
+		return map[string]EvaluationDetails{}
+	}
+	allDetails := make(map[string]EvaluationDetails, len(keys))
+	for _, key := range keys {
+		allDetails[key] = client.getValueDetails(context.Background(), conf, key, nil, user)
+	}
+
+	return allDetails
+}
+
+func (client *Client) getKeyAndValue(conf *config, variationId string) (string, interface{}) {
+	key, value, err := client.parser.parseKeyValue(conf, variationId)
 	if err != nil {
 		client.logger.Errorf(
 			"Evaluating GetKeyAndValue(%s) failed. Returning nil. %s.",
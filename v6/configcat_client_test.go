@@ -1,14 +1,19 @@
 package configcat
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
 const (
 	jsonFormat          = "{ \"f\": { \"%s\": { \"v\": %s, \"p\": [], \"r\": [] }}}"
+	typedJson           = "{ \"f\": { \"bool\": { \"v\": true, \"t\": 0, \"p\": [], \"r\": [] }, \"string\": { \"v\": \"value\", \"t\": 1, \"p\": [], \"r\": [] }, \"int\": { \"v\": 42, \"t\": 2, \"p\": [], \"r\": [] }, \"float\": { \"v\": 3.14, \"t\": 3, \"p\": [], \"r\": [] }}}"
+	detailsJson         = "{ \"f\": { \"key\": { \"v\": \"value\", \"t\": 1, \"i\": \"valueId\", \"r\": [{ \"v\": \"ruleValue\", \"a\": \"Email\", \"t\": 2, \"c\": \"@example.com\", \"i\": \"ruleId\" }], \"p\": [{ \"v\": \"percentageValue\", \"p\": 100, \"i\": \"percentageId\" }] }}}"
 	variationJsonFormat = "{ \"f\": { \"first\": { \"v\": false, \"p\": [], \"r\": [], \"i\":\"fakeIdFirst\" }, \"second\": { \"v\": true, \"p\": [], \"r\": [], \"i\":\"fakeIdSecond\" }}}"
 )
 
@@ -264,3 +269,612 @@ func TestClient_GetKeyAndValue_Empty(t *testing.T) {
 		t.Error("Expecting nil value")
 	}
 }
+
+func TestClient_GetTypedValues(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: typedJson})
+	client.Refresh()
+
+	if !client.GetBoolValue("bool", false) {
+		t.Error("Expecting true")
+	}
+
+	if client.GetStringValue("string", "") != "value" {
+		t.Error("Expecting value")
+	}
+
+	if client.GetIntValue("int", 0) != 42 {
+		t.Error("Expecting 42")
+	}
+
+	if client.GetFloatValue("float", 0) != 3.14 {
+		t.Error("Expecting 3.14")
+	}
+}
+
+func TestClient_GetTypedValues_TypeMismatch(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: typedJson})
+	client.Refresh()
+
+	if client.GetIntValue("string", 7) != 7 {
+		t.Error("Expecting default int value")
+	}
+
+	if client.GetStringValue("bool", "default") != "default" {
+		t.Error("Expecting default string value")
+	}
+
+	if client.GetIntValue("float", 7) != 7 {
+		t.Error("Expecting default int value")
+	}
+
+	if client.GetBoolValue("nonexisting", true) != true {
+		t.Error("Expecting default bool value")
+	}
+}
+
+func BenchmarkClient_GetValueForUser(b *testing.B) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: benchmarkJson(300)})
+	client.Refresh()
+	user := NewUserWithAdditionalAttributes("identifier", "someone@example.com", "", nil)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.GetValueForUser("key150", false, user)
+	}
+}
+
+func BenchmarkClient_GetAllVariationIdsForUser(b *testing.B) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: benchmarkJson(300)})
+	client.Refresh()
+	user := NewUserWithAdditionalAttributes("identifier", "someone@example.com", "", nil)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.GetAllVariationIdsForUser(user)
+	}
+}
+
+// benchmarkJson returns a configuration JSON with the given number of
+// boolean settings, each having a targeting rule and percentage options.
+func benchmarkJson(settingCount int) string {
+	settings := make([]string, settingCount)
+	for i := range settings {
+		settings[i] = fmt.Sprintf(`"key%d": { "v": false, "t": 0, "i": "id%d", `+
+			`"r": [{ "v": true, "a": "Email", "t": 2, "c": "@example.org", "i": "rule%d" }], `+
+			`"p": [{ "v": true, "p": 30, "i": "on%d" }, { "v": false, "p": 70, "i": "off%d" }] }`,
+			i, i, i, i, i)
+	}
+	return `{ "f": { ` + strings.Join(settings, ", ") + ` } }`
+}
+
+func TestClient_GetValueDetails(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: detailsJson})
+	client.Refresh()
+
+	details := client.GetValueDetails("key", "default", NewUserWithAdditionalAttributes("id", "a@example.com", "", nil))
+
+	if details.Value != "ruleValue" || details.VariationID != "ruleId" || details.IsDefaultValue {
+		t.Errorf("Expecting rule value, got %+v", details)
+	}
+
+	if details.MatchedRule == nil || details.MatchedRule.ComparisonAttribute != "Email" ||
+		details.MatchedRule.Comparator != 2 || details.MatchedRule.ComparisonValue != "@example.com" {
+		t.Errorf("Expecting matched rule, got %+v", details.MatchedRule)
+	}
+
+	if details.MatchedPercentageOption != nil {
+		t.Error("Expecting no matched percentage option")
+	}
+
+	if details.FetchTime.IsZero() {
+		t.Error("Expecting fetch time")
+	}
+}
+
+func TestClient_GetValueDetails_PercentageOption(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: detailsJson})
+	client.Refresh()
+
+	details := client.GetValueDetails("key", "default", NewUserWithAdditionalAttributes("id", "a@example.org", "", nil))
+
+	if details.Value != "percentageValue" || details.VariationID != "percentageId" {
+		t.Errorf("Expecting percentage value, got %+v", details)
+	}
+
+	if details.MatchedRule != nil {
+		t.Error("Expecting no matched rule")
+	}
+
+	if details.MatchedPercentageOption == nil || details.MatchedPercentageOption.Percentage != 100 {
+		t.Errorf("Expecting matched percentage option, got %+v", details.MatchedPercentageOption)
+	}
+}
+
+func TestClient_GetValueDetails_Default(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: detailsJson})
+	client.Refresh()
+
+	details := client.GetValueDetails("nonexisting", "default", nil)
+
+	if details.Value != "default" || !details.IsDefaultValue || details.Error == nil {
+		t.Errorf("Expecting default value with error, got %+v", details)
+	}
+}
+
+func TestClient_GetAllValues(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: typedJson})
+	client.Refresh()
+
+	values := client.GetAllValues()
+
+	if len(values) != 4 {
+		t.Errorf("Expecting 4 items, got %v", values)
+	}
+
+	if values["bool"] != true || values["string"] != "value" || values["int"] != 42.0 || values["float"] != 3.14 {
+		t.Errorf("Unexpected values %v", values)
+	}
+}
+
+func TestClient_GetAllValuesForUser(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: detailsJson})
+	client.Refresh()
+
+	values := client.GetAllValuesForUser(NewUserWithAdditionalAttributes("id", "a@example.com", "", nil))
+
+	if len(values) != 1 || values["key"] != "ruleValue" {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	allDetails := client.GetAllValueDetails(NewUserWithAdditionalAttributes("id", "a@example.org", "", nil))
+
+	if len(allDetails) != 1 || allDetails["key"].MatchedPercentageOption == nil {
+		t.Errorf("Unexpected details %v", allDetails)
+	}
+}
+
+func TestClient_GetAllValues_Empty(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Failure})
+
+	values := client.GetAllValues()
+
+	if len(values) != 0 {
+		t.Error("Expecting 0 items")
+	}
+}
+
+func TestClient_RefreshCtx_Cancelled(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponseWithDelay(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")}, time.Second*10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	status, err := client.RefreshCtx(ctx)
+
+	if _, ok := err.(*FetchError); status != Failure || !ok || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expecting deadline exceeded, got %v", err)
+	}
+
+	if client.GetValue("key", "default") != "default" {
+		t.Error("Expecting default string value")
+	}
+}
+
+func TestClient_GetValueCtx_Cancelled(t *testing.T) {
+	config := ClientConfig{Mode: LazyLoad(time.Minute, false)}
+	fetcher := newFakeConfigProvider()
+	client := newInternal("fakeKey",
+		config,
+		fetcher)
+
+	fetcher.SetResponseWithDelay(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")}, time.Second*10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	result := client.GetValueCtx(ctx, "key", "default", nil)
+
+	if result != "default" {
+		t.Error("Expecting default string value")
+	}
+
+	keys, err := client.GetAllKeysCtx(ctx)
+	if err == nil || keys != nil {
+		t.Error("Expecting context error")
+	}
+}
+
+func TestClient_Refresh_Status(t *testing.T) {
+	fetcher, client := getTestClients()
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\""), statusCode: 200})
+	status, err := client.RefreshCtx(context.Background())
+	if status != Fetched || err != nil {
+		t.Errorf("Expecting fetched, got %v %v", status, err)
+	}
+
+	fetcher.SetResponse(fetchResponse{status: NotModified, statusCode: 304})
+	status, err = client.RefreshCtx(context.Background())
+	if status != NotModified || err != nil {
+		t.Errorf("Expecting not modified, got %v %v", status, err)
+	}
+
+	fetcher.SetResponse(failedFetchResponse(403, errors.New("forbidden")))
+	status, err = client.RefreshCtx(context.Background())
+	if fetchErr, ok := err.(*FetchError); status != Failure || !ok || fetchErr.StatusCode != 403 {
+		t.Errorf("Expecting failure with status code 403, got %v %v", status, err)
+	}
+
+	if client.GetValue("key", "default") != "value" {
+		t.Error("Expecting non default string value")
+	}
+}
+
+func TestClient_Refresh_InvalidJson(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: "{", statusCode: 200})
+
+	status, err := client.RefreshCtx(context.Background())
+
+	if fetchErr, ok := err.(*FetchError); status != Failure || !ok || fetchErr.StatusCode != 200 {
+		t.Errorf("Expecting failure with parse error, got %v %v", status, err)
+	}
+}
+
+func TestClient_RefreshAsync_Status(t *testing.T) {
+	fetcher, client := getTestClients()
+	fetcher.SetResponse(failedFetchResponse(0, errors.New("connection refused")))
+
+	c := make(chan error, 1)
+	client.RefreshAsyncWithStatus(func(status FetchStatus, err error) {
+		c <- err
+	})
+
+	if err := <-c; err == nil {
+		t.Error("Expecting error")
+	}
+}
+
+func TestClient_ChangeListener(t *testing.T) {
+	changes := make(chan *ConfigChange, 2)
+	config := ClientConfig{Mode: ManualPoll(), ChangeListener: func(change *ConfigChange) {
+		changes <- change
+	}}
+	fetcher := newFakeConfigProvider()
+	client := newInternal("fakeKey",
+		config,
+		fetcher)
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client.Refresh()
+	change := <-changes
+
+	if oldKeys, _ := change.Old.GetAllKeys(); !reflect.DeepEqual(change.Added, []string{"key"}) || len(oldKeys) != 0 {
+		t.Errorf("Unexpected change %+v", change)
+	}
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client.Refresh()
+	// A new body without any changed setting isn't reported.
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"") + " "})
+	client.Refresh()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value2\"")})
+	client.Refresh()
+	change = <-changes
+
+	if !reflect.DeepEqual(change.Modified, []string{"key"}) {
+		t.Errorf("Expecting key to be modified, got %+v", change)
+	}
+
+	if change.Old.GetValue("key", "") != "value" || change.New.GetValue("key", "") != "value2" {
+		t.Error("Expecting old and new values")
+	}
+
+	if change.New.FetchTime().IsZero() {
+		t.Error("Expecting fetch time")
+	}
+
+	select {
+	case change := <-changes:
+		t.Errorf("Unexpected change %+v", change)
+	default:
+	}
+}
+
+func TestClient_ChangeListener_LazyLoad(t *testing.T) {
+	changes := make(chan *ConfigChange, 1)
+	config := ClientConfig{Mode: LazyLoad(time.Minute, false), ChangeListener: func(change *ConfigChange) {
+		changes <- change
+	}}
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client := newInternal("fakeKey",
+		config,
+		fetcher)
+
+	client.GetValue("key", "default")
+	change := <-changes
+
+	if !reflect.DeepEqual(change.Added, []string{"key"}) {
+		t.Errorf("Unexpected change %+v", change)
+	}
+}
+
+func TestClient_ChangeListener_AutoPoll(t *testing.T) {
+	changes := make(chan *ConfigChange, 1)
+	config := ClientConfig{Mode: AutoPoll(time.Minute), ChangeListener: func(change *ConfigChange) {
+		changes <- change
+	}}
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client := newInternal("fakeKey",
+		config,
+		fetcher)
+	defer client.Close()
+
+	change := <-changes
+
+	if change.New.GetValue("key", "default") != "value" {
+		t.Errorf("Unexpected change %+v", change)
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	fetcher, client := getTestClients()
+	defer client.Close()
+
+	first := make(chan *ConfigChange, 2)
+	second := make(chan *ConfigChange, 2)
+	unsubscribeFirst := client.Subscribe(func(change *ConfigChange) {
+		first <- change
+	})
+	client.Subscribe(func(change *ConfigChange) {
+		panic("listener failure")
+	})
+	client.Subscribe(func(change *ConfigChange) {
+		second <- change
+	})
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client.Refresh()
+
+	if (<-first).New.GetValue("key", "") != "value" || (<-second).New.GetValue("key", "") != "value" {
+		t.Error("Expecting both listeners to be notified")
+	}
+
+	unsubscribeFirst()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value2\"")})
+	client.Refresh()
+
+	if (<-second).New.GetValue("key", "") != "value2" {
+		t.Error("Expecting the remaining listener to be notified")
+	}
+
+	select {
+	case change := <-first:
+		t.Errorf("Unexpected change after unsubscribe %+v", change)
+	default:
+	}
+}
+
+func TestClient_Subscribe_SlowListenerDoesNotBlockRefresh(t *testing.T) {
+	fetcher, client := getTestClients()
+	defer client.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	client.Subscribe(func(change *ConfigChange) {
+		<-release
+	})
+
+	for i := 0; i < 3; i++ {
+		fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", fmt.Sprintf("\"value%d\"", i))})
+		done := make(chan struct{})
+		go func() {
+			client.Refresh()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Refresh blocked by a slow listener")
+		}
+	}
+}
+
+func TestClient_Subscribe_SlowListenerGetsMergedChanges(t *testing.T) {
+	fetcher, client := getTestClients()
+	defer client.Close()
+
+	changes := make(chan *ConfigChange, 3)
+	release := make(chan struct{})
+	client.Subscribe(func(change *ConfigChange) {
+		changes <- change
+		<-release
+	})
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value0\"")})
+	client.Refresh()
+	<-changes
+	for i := 1; i <= 3; i++ {
+		fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", fmt.Sprintf("\"value%d\"", i))})
+		client.Refresh()
+	}
+	close(release)
+
+	merged := <-changes
+	if merged.Old.GetValue("key", "") != "value0" || merged.New.GetValue("key", "") != "value3" ||
+		!reflect.DeepEqual(merged.Modified, []string{"key"}) {
+		t.Errorf("Expecting a merged change from value0 to value3, got %v => %v %+v",
+			merged.Old.GetValue("key", ""), merged.New.GetValue("key", ""), merged)
+	}
+	select {
+	case change := <-changes:
+		t.Errorf("Unexpected change after the merged one %+v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_Subscribe_DropsRevertedPendingChanges(t *testing.T) {
+	fetcher, client := getTestClients()
+	defer client.Close()
+
+	changes := make(chan *ConfigChange, 3)
+	release := make(chan struct{})
+	client.Subscribe(func(change *ConfigChange) {
+		changes <- change
+		<-release
+	})
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value0\"")})
+	client.Refresh()
+	<-changes
+	for _, value := range []string{"value1", "value0"} {
+		fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\""+value+"\"")})
+		client.Refresh()
+	}
+	close(release)
+
+	select {
+	case change := <-changes:
+		t.Errorf("Unexpected change after a reverted one %+v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_Offline_ManualPoll(t *testing.T) {
+	fetcher, client := getTestClients()
+	defer client.Close()
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client.Refresh()
+
+	client.SetOffline()
+	if !client.IsOffline() {
+		t.Error("Expecting the client to be offline")
+	}
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value2\"")})
+	status, err := client.RefreshCtx(context.Background())
+	if status != NotModified || err != nil {
+		t.Errorf("Expecting the refresh to be skipped, got %v, %v", status, err)
+	}
+	if result := client.GetValue("key", "default"); result != "value" {
+		t.Errorf("Expecting the cached value, got %v", result)
+	}
+
+	client.SetOnline()
+	if client.IsOffline() {
+		t.Error("Expecting the client to be online")
+	}
+	client.Refresh()
+	if result := client.GetValue("key", "default"); result != "value2" {
+		t.Errorf("Expecting the fetched value, got %v", result)
+	}
+}
+
+func TestClient_Offline_AutoPoll(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client := newInternal("fakeKey", ClientConfig{Mode: AutoPoll(time.Hour), Offline: true}, fetcher)
+	defer client.Close()
+
+	if result := client.GetValue("key", "default"); result != "default" {
+		t.Errorf("Expecting no configuration to be fetched while offline, got %v", result)
+	}
+
+	client.SetOnline()
+	deadline := time.Now().Add(time.Second)
+	for client.GetValue("key", "default") != "value" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expecting a poll when going online")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClient_Offline_LazyLoad(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client := newInternal("fakeKey", ClientConfig{Mode: LazyLoad(time.Hour, false), Offline: true}, fetcher)
+	defer client.Close()
+
+	if result := client.GetValue("key", "default"); result != "default" {
+		t.Errorf("Expecting no configuration to be fetched while offline, got %v", result)
+	}
+
+	client.SetOnline()
+	if result := client.GetValue("key", "default"); result != "value" {
+		t.Errorf("Expecting the configuration to be fetched when online, got %v", result)
+	}
+}
+
+// blockingSetCache is a ConfigCacheCtx whose Set blocks until it's released or its context is done.
+type blockingSetCache struct {
+	inMemory *InMemoryCache
+	setting  chan struct{}
+	release  chan struct{}
+}
+
+func (cache *blockingSetCache) Get(ctx context.Context, key string) (string, error) {
+	return cache.inMemory.Get(ctx, key)
+}
+
+func (cache *blockingSetCache) Set(ctx context.Context, key string, value string) error {
+	close(cache.setting)
+	select {
+	case <-cache.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return cache.inMemory.Set(ctx, key, value)
+}
+
+func TestClient_CacheCtx_SlowCacheDoesNotBlockReaders(t *testing.T) {
+	cache := &blockingSetCache{inMemory: NewInMemoryCache(), setting: make(chan struct{}), release: make(chan struct{})}
+	fetcher := newFakeConfigProvider()
+	client := newInternal("fakeKey", ClientConfig{Mode: ManualPoll(), CacheCtx: cache}, fetcher)
+	defer client.Close()
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	ctx, cancel := context.WithCancel(context.Background())
+	refreshed := make(chan struct{})
+	go func() {
+		client.RefreshCtx(ctx)
+		close(refreshed)
+	}()
+	<-cache.setting
+
+	values := make(chan interface{})
+	go func() {
+		values <- client.GetValue("key", "default")
+	}()
+	select {
+	case value := <-values:
+		if value != "value" {
+			t.Errorf("Expecting the fetched value during the cache write, got %v", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetValue blocked by a slow cache write")
+	}
+
+	cancel()
+	close(cache.release)
+	<-refreshed
+	if value := client.GetValue("key", "default"); value != "value" {
+		t.Errorf("Expecting the fetched value after the caller gave up, got %v", value)
+	}
+	if entry, _ := cache.inMemory.Get(context.Background(), client.refreshPolicy.(*manualPollingPolicy).cacheKey); len(entry) == 0 {
+		t.Error("Expecting the cache write not to be cancelled with the caller")
+	}
+}
@@ -0,0 +1,291 @@
+// Package configcatotel records the setting evaluations and the configuration fetches
+// of a ConfigCat client with OpenTelemetry.
+//
+// The evaluations are recorded as feature_flag events on the span in the context passed
+// to the Ctx getters of the client, e.g. Client.GetValueCtx. The fetches are recorded as
+// client spans, along with metrics of their duration, outcome and size:
+//
+//	config := configcat.ClientConfig{}
+//	if err := configcatotel.Instrument(&config); err != nil {
+//		return err
+//	}
+//	client := configcat.NewCustomClient("#YOUR-SDK-KEY#", config)
+//
+// The module requires the SDK v6.1.0, so it can't be used outside of this repository
+// until that version is tagged.
+package configcatotel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/configcat/go-sdk/v6"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/configcat/go-sdk/v6/configcatotel"
+	providerName        = "ConfigCat"
+)
+
+// The outcomes of the fetches, recorded in the FetchStatusKey attribute.
+const (
+	fetchStatusFetched     = "fetched"
+	fetchStatusNotModified = "not_modified"
+	fetchStatusFailed      = "failed"
+)
+
+// The keys of the recorded attributes.
+const (
+	FeatureFlagKeyKey          = attribute.Key("feature_flag.key")
+	FeatureFlagVariantKey      = attribute.Key("feature_flag.variant")
+	FeatureFlagProviderNameKey = attribute.Key("feature_flag.provider_name")
+	ErrorMessageKey            = attribute.Key("error.message")
+	HTTPMethodKey              = attribute.Key("http.request.method")
+	HTTPStatusCodeKey          = attribute.Key("http.response.status_code")
+	URLKey                     = attribute.Key("url.full")
+	// ETagKey holds the ETag of the fetched configuration.
+	ETagKey = attribute.Key("configcat.etag")
+	// FetchStatusKey holds the outcome of a fetch: fetched, not_modified or failed.
+	FetchStatusKey = attribute.Key("configcat.fetch.status")
+)
+
+// Option configures the instrumentation.
+type Option func(*options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the provider of the tracer recording the fetch spans.
+// Default: the global provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(opts *options) {
+		opts.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the provider of the meter recording the fetch metrics.
+// Default: the global provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(opts *options) {
+		opts.meterProvider = provider
+	}
+}
+
+func newOptions(opts []Option) options {
+	result := options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&result)
+	}
+	return result
+}
+
+// Instrument sets up the client configuration to record the evaluations and the fetches:
+// it adds the hook of NewEvaluationHook and wraps the transport with NewTransport.
+func Instrument(config *configcat.ClientConfig, opts ...Option) error {
+	transport, err := NewTransport(config.Transport, opts...)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
+	config.Hooks = append(config.Hooks, NewEvaluationHook())
+	return nil
+}
+
+// NewEvaluationHook returns an EvaluationHook which adds a feature_flag event to the span
+// in the context of every evaluation, with the key of the setting, the evaluated
+// Variation ID as variant, and the error message when the evaluation failed.
+// Nothing is recorded when the span isn't recording.
+func NewEvaluationHook() configcat.EvaluationHook {
+	return evaluationHook{}
+}
+
+type evaluationHook struct{}
+
+func (evaluationHook) BeforeEvaluation(ctx context.Context, key string, user *configcat.User, defaultValue interface{}) {
+}
+
+func (evaluationHook) AfterEvaluation(ctx context.Context, details configcat.EvaluationDetails, defaultValue interface{}) {
+	addEvaluationEvent(ctx, details)
+}
+
+func (evaluationHook) EvaluationFailed(ctx context.Context, details configcat.EvaluationDetails, defaultValue interface{}) {
+	addEvaluationEvent(ctx, details)
+}
+
+func addEvaluationEvent(ctx context.Context, details configcat.EvaluationDetails) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	attributes := []attribute.KeyValue{
+		FeatureFlagKeyKey.String(details.Key),
+		FeatureFlagProviderNameKey.String(providerName),
+	}
+	if details.Error != nil {
+		attributes = append(attributes, ErrorMessageKey.String(details.Error.Error()))
+	} else if len(details.VariationID) > 0 {
+		attributes = append(attributes, FeatureFlagVariantKey.String(details.VariationID))
+	} else {
+		attributes = append(attributes, FeatureFlagVariantKey.String(fmt.Sprint(details.Value)))
+	}
+	span.AddEvent("feature_flag", trace.WithAttributes(attributes...))
+}
+
+// transport is an http.RoundTripper recording the configuration fetches.
+type transport struct {
+	base     http.RoundTripper
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	requests metric.Int64Counter
+	size     metric.Int64Histogram
+}
+
+// NewTransport wraps the base transport of the ConfigCat client, http.DefaultTransport when
+// it's nil, to record a span for every configuration fetch with the HTTP status code and the
+// ETag of the response. It also records the metrics
+//
+//	configcat.fetch.duration: the duration of the fetches in seconds,
+//	configcat.fetch.requests: the number of the fetches by their outcome in the configcat.fetch.status
+//	attribute, so the ratio of the failures and the 304 Not Modified responses can be derived,
+//	configcat.config.size: the size of the fetched configurations in bytes.
+func NewTransport(base http.RoundTripper, opts ...Option) (http.RoundTripper, error) {
+	options := newOptions(opts)
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	meter := options.meterProvider.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram("configcat.fetch.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("The duration of the configuration fetches."))
+	if err != nil {
+		return nil, err
+	}
+	requests, err := meter.Int64Counter("configcat.fetch.requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("The number of the configuration fetches by their outcome."))
+	if err != nil {
+		return nil, err
+	}
+	size, err := meter.Int64Histogram("configcat.config.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("The size of the fetched configurations."))
+	if err != nil {
+		return nil, err
+	}
+
+	return &transport{
+		base:     base,
+		tracer:   options.tracerProvider.Tracer(instrumentationName),
+		duration: duration,
+		requests: requests,
+		size:     size,
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper. The span of the fetch ends when the body
+// of the response is closed, so it includes reading the configuration, or right away
+// when the response has no body to read.
+func (t *transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	start := time.Now()
+	ctx, span := t.tracer.Start(request.Context(), "configcat.fetch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			HTTPMethodKey.String(request.Method),
+			URLKey.String(request.URL.String()),
+		))
+
+	response, err := t.base.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		t.record(ctx, start, fetchStatusFailed, 0)
+		return nil, err
+	}
+
+	span.SetAttributes(HTTPStatusCodeKey.Int(response.StatusCode))
+	if eTag := response.Header.Get("ETag"); len(eTag) > 0 {
+		span.SetAttributes(ETagKey.String(eTag))
+	}
+	done := func(size int64, readErr error) {
+		status := fetchStatusFor(response.StatusCode)
+		if readErr != nil {
+			span.RecordError(readErr)
+			status = fetchStatusFailed
+		}
+		if status == fetchStatusFailed {
+			span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+		}
+		span.End()
+		t.record(ctx, start, status, size)
+	}
+	if response.StatusCode == http.StatusNotModified || response.Body == http.NoBody {
+		// There's nothing to read, so the fetch is recorded right away.
+		done(0, nil)
+		return response, nil
+	}
+	response.Body = &recordingBody{ReadCloser: response.Body, done: done}
+	return response, nil
+}
+
+func (t *transport) record(ctx context.Context, start time.Time, status string, size int64) {
+	attributes := metric.WithAttributes(FetchStatusKey.String(status))
+	t.duration.Record(ctx, time.Since(start).Seconds(), attributes)
+	t.requests.Add(ctx, 1, attributes)
+	if status == fetchStatusFetched {
+		t.size.Record(ctx, size)
+	}
+}
+
+func fetchStatusFor(statusCode int) string {
+	switch {
+	case statusCode == http.StatusNotModified:
+		return fetchStatusNotModified
+	case statusCode >= 200 && statusCode < 300:
+		return fetchStatusFetched
+	}
+	return fetchStatusFailed
+}
+
+// recordingBody counts the bytes read from a response body,
+// and calls done once when it's closed.
+type recordingBody struct {
+	io.ReadCloser
+	size    int64
+	readErr error
+	once    sync.Once
+	done    func(size int64, readErr error)
+}
+
+func (body *recordingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.size += int64(n)
+	if err != nil && err != io.EOF && body.readErr == nil {
+		body.readErr = err
+	}
+	return n, err
+}
+
+func (body *recordingBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.once.Do(func() {
+		body.done(body.size, body.readErr)
+	})
+	return err
+}
@@ -0,0 +1,181 @@
+package configcatotel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/configcat/go-sdk/v6"
+	"github.com/configcat/go-sdk/v6/configcattest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const sdkKey = "fake-sdk-key"
+
+type testTelemetry struct {
+	spans          *tracetest.SpanRecorder
+	tracerProvider *sdktrace.TracerProvider
+	reader         *sdkmetric.ManualReader
+}
+
+func newTestTelemetry() *testTelemetry {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	return &testTelemetry{
+		spans:          spans,
+		tracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		reader:         reader,
+	}
+}
+
+func (telemetry *testTelemetry) newClient(t *testing.T, srv *configcattest.Server) *configcat.Client {
+	config := configcat.ClientConfig{
+		BaseUrl: srv.URL,
+		Mode:    configcat.ManualPoll(),
+		Logger:  configcat.DefaultLogger(configcat.LogLevelPanic),
+	}
+	err := Instrument(&config,
+		WithTracerProvider(telemetry.tracerProvider),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(telemetry.reader))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configcat.NewCustomClient(sdkKey, config)
+}
+
+// metrics collects the recorded metrics keyed by their names.
+func (telemetry *testTelemetry) metrics(t *testing.T) map[string]metricdata.Aggregation {
+	var data metricdata.ResourceMetrics
+	if err := telemetry.reader.Collect(context.Background(), &data); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+func attributeValue(attributes []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTransport_RecordsFetches(t *testing.T) {
+	srv := configcattest.NewServer()
+	defer srv.Close()
+	srv.SetFlags(sdkKey, map[string]*configcattest.Flag{"key": {Default: "value"}})
+	telemetry := newTestTelemetry()
+	client := telemetry.newClient(t, srv)
+	defer client.Close()
+
+	client.Refresh()
+	client.Refresh()
+	srv.FailNext(1, http.StatusInternalServerError)
+	client.Refresh()
+
+	spans := telemetry.spans.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expecting a span for each fetch, got %d", len(spans))
+	}
+	for i, expected := range []int{200, 304, 500} {
+		if code, _ := attributeValue(spans[i].Attributes(), HTTPStatusCodeKey); code.AsInt64() != int64(expected) {
+			t.Errorf("Expecting the status code %d in span %d, got %v", expected, i, code.AsInt64())
+		}
+	}
+	if eTag, ok := attributeValue(spans[0].Attributes(), ETagKey); !ok || len(eTag.AsString()) == 0 {
+		t.Error("Expecting the ETag of the fetched configuration")
+	}
+	if spans[0].Status().Code == codes.Error || spans[2].Status().Code != codes.Error {
+		t.Errorf("Expecting only the failed fetch to have an error status, got %v and %v", spans[0].Status(), spans[2].Status())
+	}
+
+	metrics := telemetry.metrics(t)
+	requests, ok := metrics["configcat.fetch.requests"].(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("Expecting the fetch counter, got %v", metrics)
+	}
+	counts := map[string]int64{}
+	for _, point := range requests.DataPoints {
+		status, _ := point.Attributes.Value(FetchStatusKey)
+		counts[status.AsString()] = point.Value
+	}
+	if counts[fetchStatusFetched] != 1 || counts[fetchStatusNotModified] != 1 || counts[fetchStatusFailed] != 1 {
+		t.Errorf("Expecting one fetch of each outcome, got %v", counts)
+	}
+
+	size, ok := metrics["configcat.config.size"].(metricdata.Histogram[int64])
+	if !ok || len(size.DataPoints) != 1 || size.DataPoints[0].Count != 1 || size.DataPoints[0].Sum == 0 {
+		t.Errorf("Expecting the size of the fetched configuration, got %+v", metrics["configcat.config.size"])
+	}
+	if _, ok := metrics["configcat.fetch.duration"].(metricdata.Histogram[float64]); !ok {
+		t.Error("Expecting the fetch durations")
+	}
+}
+
+func TestTransport_RecordsBrokenConnections(t *testing.T) {
+	srv := configcattest.NewServer()
+	defer srv.Close()
+	srv.SetFlags(sdkKey, map[string]*configcattest.Flag{"key": {Default: "value"}})
+	telemetry := newTestTelemetry()
+	client := telemetry.newClient(t, srv)
+	defer client.Close()
+
+	srv.FailNext(1, 0)
+	client.Refresh()
+
+	spans := telemetry.spans.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Fatalf("Expecting a failed span for the broken fetch, got %v", spans)
+	}
+}
+
+func TestEvaluationHook_AddsSpanEvents(t *testing.T) {
+	srv := configcattest.NewServer()
+	defer srv.Close()
+	srv.SetFlags(sdkKey, map[string]*configcattest.Flag{"key": {Default: true, VariationID: "on"}})
+	telemetry := newTestTelemetry()
+	client := telemetry.newClient(t, srv)
+	defer client.Close()
+	client.Refresh()
+
+	ctx, span := telemetry.tracerProvider.Tracer("test").Start(context.Background(), "request")
+	client.GetValueCtx(ctx, "key", false, nil)
+	client.GetValueCtx(ctx, "missing", false, nil)
+	client.GetValue("key", false)
+	span.End()
+
+	var events []sdktrace.Event
+	for _, ended := range telemetry.spans.Ended() {
+		if ended.Name() == "request" {
+			events = ended.Events()
+		}
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expecting an event for each evaluation in the span context, got %d", len(events))
+	}
+
+	if key, _ := attributeValue(events[0].Attributes, FeatureFlagKeyKey); key.AsString() != "key" {
+		t.Errorf("Expecting the key of the setting, got %q", key.AsString())
+	}
+	if variant, _ := attributeValue(events[0].Attributes, FeatureFlagVariantKey); variant.AsString() != "on" {
+		t.Errorf("Expecting the Variation ID as variant, got %q", variant.AsString())
+	}
+	if provider, _ := attributeValue(events[0].Attributes, FeatureFlagProviderNameKey); provider.AsString() != providerName {
+		t.Errorf("Expecting the provider name, got %q", provider.AsString())
+	}
+	if _, ok := attributeValue(events[1].Attributes, ErrorMessageKey); !ok {
+		t.Error("Expecting the error message of the failed evaluation")
+	}
+}
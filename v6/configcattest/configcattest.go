@@ -0,0 +1,318 @@
+// Package configcattest provides an in-process fake of the ConfigCat CDN,
+// so the ConfigCat client can be tested end-to-end without network access.
+//
+// A typical test starts a Server, sets the flags served for an SDK key,
+// and points the client at the server:
+//
+//	srv := configcattest.NewServer()
+//	defer srv.Close()
+//	srv.SetFlags("sdk-key", map[string]*configcattest.Flag{
+//		"isFeatureEnabled": {Default: false, Rules: []configcattest.Rule{{
+//			ComparisonAttribute: "Email",
+//			Comparator:          configcat.Contains,
+//			ComparisonValue:     "@example.com",
+//			Value:               true,
+//		}}},
+//	})
+//	client := configcat.NewCustomClient("sdk-key", configcat.ClientConfig{BaseUrl: srv.URL})
+package configcattest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/configcat/go-sdk/v6"
+)
+
+// Flag describes a feature flag or setting served by the fake server.
+type Flag struct {
+	// Default holds the value served when no rule or percentage option applies.
+	// Its type, one of bool, string, int or float64, determines the type of the flag.
+	Default interface{}
+	// Rules holds the targeting rules, evaluated in order.
+	Rules []Rule
+	// Percentages holds the percentage options, applied when no rule matches.
+	// The percentages must add up to 100.
+	Percentages []PercentageOption
+	// VariationID identifies the default value.
+	VariationID string
+}
+
+// Rule describes a targeting rule of a Flag.
+type Rule struct {
+	// ComparisonAttribute holds the name of the user attribute the rule compares, e.g. "Email".
+	ComparisonAttribute string
+	Comparator          configcat.Comparator
+	// ComparisonValue holds the value the user attribute is compared to, e.g. a comma separated list.
+	// The values of the sensitive comparators are given in plain text; they're hashed when the flags are set.
+	ComparisonValue string
+	// Value holds the value served when the rule matches. It must have the type of the flag.
+	Value interface{}
+	// VariationID identifies the value served when the rule matches.
+	VariationID string
+}
+
+// PercentageOption describes a percentage option of a Flag.
+type PercentageOption struct {
+	// Percentage holds the share of users falling into this option.
+	Percentage int64
+	// Value holds the value served for the users falling into this option. It must have the type of the flag.
+	Value interface{}
+	// VariationID identifies the value served by this option.
+	VariationID string
+}
+
+// Handler is an http.Handler serving the configuration files of the ConfigCat CDN.
+// It answers conditional requests with 304 Not Modified when the configuration didn't change.
+// The zero Handler serves no configuration.
+type Handler struct {
+	mu       sync.Mutex
+	configs  map[string]*servedConfig
+	failures int
+	failWith int
+	latency  time.Duration
+	requests int
+}
+
+// preferences holds the data governance preferences served for an SDK key.
+type preferences struct {
+	url      string
+	redirect int
+}
+
+// servedConfig holds the configuration served for an SDK key.
+type servedConfig struct {
+	flags       map[string]*Flag
+	preferences *preferences
+	jsonBody    []byte
+	eTag        string
+}
+
+// SetFlags sets the flags served for the SDK key, replacing the earlier ones.
+// The flags are copied, so changing them afterwards doesn't affect the served configuration.
+// It fails when a flag is invalid, e.g. when the types of its values don't match.
+func (h *Handler) SetFlags(sdkKey string, flags map[string]*Flag) error {
+	copied, err := copyFlags(flags)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	served := h.served(sdkKey)
+	previous := served.flags
+	served.flags = copied
+	if err := served.marshal(); err != nil {
+		served.flags = previous
+		return err
+	}
+	return nil
+}
+
+// SetConfigJSON sets the configuration JSON served for the SDK key as it is,
+// e.g. to serve a configuration which the Flag type can't describe.
+func (h *Handler) SetConfigJSON(sdkKey string, jsonBody string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	served := h.served(sdkKey)
+	served.flags = nil
+	served.preferences = nil
+	served.setBody([]byte(jsonBody))
+}
+
+// SetRedirect makes the configuration served for the SDK key point the clients to baseUrl,
+// as the data governance preferences of the CDN do. The redirect is one of
+// configcat.NoRedirect, configcat.ShouldRedirect and configcat.ForceRedirect.
+// The flags are kept, but a configuration set by SetConfigJSON is replaced.
+func (h *Handler) SetRedirect(sdkKey string, baseUrl string, redirect int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	served := h.served(sdkKey)
+	previous := served.preferences
+	served.preferences = &preferences{url: baseUrl, redirect: redirect}
+	if err := served.marshal(); err != nil {
+		served.preferences = previous
+		return err
+	}
+	return nil
+}
+
+// FailNext makes the next n requests fail with the given HTTP status code.
+// When statusCode is 0, the connection is broken in the middle of the response.
+func (h *Handler) FailNext(n int, statusCode int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures = n
+	h.failWith = statusCode
+}
+
+// SetLatency delays all the following responses by d.
+func (h *Handler) SetLatency(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.latency = d
+}
+
+// Requests returns the number of the requests served so far.
+func (h *Handler) Requests() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sdkKey, ok := sdkKeyOf(r.URL.Path)
+	if r.Method != http.MethodGet || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mu.Lock()
+	h.requests++
+	latency := h.latency
+	failWith, fail := h.failWith, h.failures > 0
+	if fail {
+		h.failures--
+	}
+	var jsonBody []byte
+	var eTag string
+	if served := h.configs[sdkKey]; served != nil {
+		jsonBody, eTag = served.jsonBody, served.eTag
+	}
+	h.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch {
+	case fail && failWith == 0:
+		breakConnection(w)
+	case fail:
+		http.Error(w, http.StatusText(failWith), failWith)
+	case jsonBody == nil:
+		http.NotFound(w, r)
+	case r.Header.Get("If-None-Match") == eTag:
+		w.WriteHeader(http.StatusNotModified)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", eTag)
+		w.Write(jsonBody)
+	}
+}
+
+func (h *Handler) served(sdkKey string) *servedConfig {
+	if h.configs == nil {
+		h.configs = make(map[string]*servedConfig)
+	}
+	served := h.configs[sdkKey]
+	if served == nil {
+		served = &servedConfig{}
+		h.configs[sdkKey] = served
+	}
+	return served
+}
+
+// sdkKeyOf returns the SDK key of a /configuration-files/{sdkKey}/config_v5.json path.
+func sdkKeyOf(path string) (string, bool) {
+	const prefix, suffix = "/configuration-files/", "/" + configcat.ConfigJsonName + ".json"
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		return "", false
+	}
+	sdkKey := path[len(prefix) : len(path)-len(suffix)]
+	return sdkKey, len(sdkKey) > 0 && !strings.Contains(sdkKey, "/")
+}
+
+// breakConnection closes the connection after sending a part of the response. Closing it
+// without a response wouldn't do, as the HTTP client may retry the request transparently.
+func breakConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 1024\r\n\r\n{")
+	buf.Flush()
+	conn.Close()
+}
+
+// Server is a Handler served by an httptest.Server.
+type Server struct {
+	*Handler
+	// URL holds the base URL of the server, to be set as configcat.ClientConfig.BaseUrl.
+	URL    string
+	server *httptest.Server
+}
+
+// NewServer starts a Server serving no configuration. It should be closed when it's no longer used.
+func NewServer() *Server {
+	handler := &Handler{}
+	server := httptest.NewServer(handler)
+	return &Server{Handler: handler, URL: server.URL, server: server}
+}
+
+// Close shuts down the server and blocks until all the outstanding requests have completed.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// marshal renders the flags and preferences to the configuration JSON.
+func (served *servedConfig) marshal() error {
+	builder := configcat.NewConfigBuilder()
+	for key, flag := range served.flags {
+		flag.addTo(builder.Setting(key, flag.Default).WithVariationID(flag.VariationID))
+	}
+	if served.preferences != nil {
+		builder.Redirect(served.preferences.url, served.preferences.redirect)
+	}
+	jsonBody, err := builder.JSON()
+	if err != nil {
+		return err
+	}
+	served.setBody([]byte(jsonBody))
+	return nil
+}
+
+func (served *servedConfig) setBody(jsonBody []byte) {
+	hash := sha1.Sum(jsonBody)
+	served.jsonBody = jsonBody
+	served.eTag = `"` + hex.EncodeToString(hash[:]) + `"`
+}
+
+// copyFlags returns a deep copy of the flags.
+func copyFlags(flags map[string]*Flag) (map[string]*Flag, error) {
+	copied := make(map[string]*Flag, len(flags))
+	for key, flag := range flags {
+		if flag == nil {
+			return nil, fmt.Errorf("nil flag %s", key)
+		}
+		flagCopy := *flag
+		flagCopy.Rules = append([]Rule(nil), flag.Rules...)
+		flagCopy.Percentages = append([]PercentageOption(nil), flag.Percentages...)
+		copied[key] = &flagCopy
+	}
+	return copied, nil
+}
+
+func (flag *Flag) addTo(setting *configcat.SettingBuilder) {
+	for _, rule := range flag.Rules {
+		setting.Rule(rule.ComparisonAttribute, rule.Comparator, rule.ComparisonValue, rule.Value).WithVariationID(rule.VariationID)
+	}
+	for _, option := range flag.Percentages {
+		setting.Percentage(option.Percentage, option.Value).WithVariationID(option.VariationID)
+	}
+}
@@ -0,0 +1,222 @@
+package configcattest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/configcat/go-sdk/v6"
+)
+
+const sdkKey = "fake-sdk-key"
+
+func newTestClient(srv *Server, config configcat.ClientConfig) *configcat.Client {
+	config.BaseUrl = srv.URL
+	config.Mode = configcat.ManualPoll()
+	config.Logger = configcat.DefaultLogger(configcat.LogLevelPanic)
+	return configcat.NewCustomClient(sdkKey, config)
+}
+
+func TestServer_ServesFlags(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	err := srv.SetFlags(sdkKey, map[string]*Flag{
+		"bool": {Default: false, VariationID: "off", Rules: []Rule{{
+			ComparisonAttribute: "Email",
+			Comparator:          configcat.Contains,
+			ComparisonValue:     "@example.com",
+			Value:               true,
+			VariationID:         "on",
+		}}},
+		"secret": {Default: "public", Rules: []Rule{{
+			ComparisonAttribute: "Identifier",
+			Comparator:          configcat.OneOfSensitive,
+			ComparisonValue:     "alice, bob",
+			Value:               "secret",
+		}}},
+		"int":   {Default: 1, Percentages: []PercentageOption{{Percentage: 100, Value: 2}}},
+		"float": {Default: 1.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(srv, configcat.ClientConfig{})
+	defer client.Close()
+	if _, err := client.RefreshCtx(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	user := configcat.NewUserWithAdditionalAttributes("bob", "bob@example.com", "", nil)
+	if value := client.GetBoolValueForUser("bool", false, user); !value {
+		t.Error("Expecting the targeting rule to match")
+	}
+	if id := client.GetVariationIdForUser("bool", "", configcat.NewUser("carol")); id != "off" {
+		t.Errorf("Expecting the default variation, got %q", id)
+	}
+	if value := client.GetStringValueForUser("secret", "", user); value != "secret" {
+		t.Errorf("Expecting the sensitive rule to match, got %q", value)
+	}
+	if value := client.GetIntValueForUser("int", 0, user); value != 2 {
+		t.Errorf("Expecting the percentage option, got %v", value)
+	}
+	if value := client.GetFloatValue("float", 0); value != 1.5 {
+		t.Errorf("Expecting the default value, got %v", value)
+	}
+}
+
+func TestServer_NotModified(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetFlags(sdkKey, map[string]*Flag{"key": {Default: "first"}})
+	client := newTestClient(srv, configcat.ClientConfig{})
+	defer client.Close()
+
+	if status, _ := client.RefreshCtx(context.Background()); status != configcat.Fetched {
+		t.Errorf("Expecting the first refresh to fetch, got %v", status)
+	}
+	if status, _ := client.RefreshCtx(context.Background()); status != configcat.NotModified {
+		t.Errorf("Expecting the configuration not to be modified, got %v", status)
+	}
+
+	srv.SetFlags(sdkKey, map[string]*Flag{"key": {Default: "second"}})
+	if status, _ := client.RefreshCtx(context.Background()); status != configcat.Fetched {
+		t.Errorf("Expecting the changed configuration to be fetched, got %v", status)
+	}
+	if value := client.GetStringValue("key", ""); value != "second" {
+		t.Errorf("Expecting the changed value, got %q", value)
+	}
+	if requests := srv.Requests(); requests != 3 {
+		t.Errorf("Expecting 3 requests, got %d", requests)
+	}
+}
+
+func TestServer_FailNext(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetFlags(sdkKey, map[string]*Flag{"key": {Default: true}})
+	client := newTestClient(srv, configcat.ClientConfig{})
+	defer client.Close()
+
+	srv.FailNext(1, http.StatusInternalServerError)
+	if status, err := client.RefreshCtx(context.Background()); status != configcat.Failure || err == nil {
+		t.Errorf("Expecting the refresh to fail, got %v, %v", status, err)
+	}
+	srv.FailNext(1, 0)
+	if status, err := client.RefreshCtx(context.Background()); status != configcat.Failure || err == nil {
+		t.Errorf("Expecting the refresh to fail on the closed connection, got %v, %v", status, err)
+	}
+	if _, err := client.RefreshCtx(context.Background()); err != nil {
+		t.Errorf("Expecting the refresh to succeed after the failures, got %v", err)
+	}
+}
+
+func TestServer_Latency(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetFlags(sdkKey, map[string]*Flag{"key": {Default: true}})
+	srv.SetLatency(time.Second)
+	client := newTestClient(srv, configcat.ClientConfig{HttpTimeout: 50 * time.Millisecond})
+	defer client.Close()
+
+	if status, _ := client.RefreshCtx(context.Background()); status != configcat.Failure {
+		t.Errorf("Expecting the refresh to time out, got %v", status)
+	}
+}
+
+func TestServer_Redirect(t *testing.T) {
+	srv, eu := NewServer(), NewServer()
+	defer srv.Close()
+	defer eu.Close()
+	srv.SetFlags(sdkKey, map[string]*Flag{"key": {Default: "global"}})
+	if err := srv.SetRedirect(sdkKey, eu.URL, configcat.ForceRedirect); err != nil {
+		t.Fatal(err)
+	}
+	eu.SetFlags(sdkKey, map[string]*Flag{"key": {Default: "eu"}})
+
+	client := newTestClient(srv, configcat.ClientConfig{})
+	defer client.Close()
+	client.Refresh()
+
+	if value := client.GetStringValue("key", ""); value != "eu" {
+		t.Errorf("Expecting the value of the redirected server, got %q", value)
+	}
+	if srv.Requests() != 1 || eu.Requests() != 1 {
+		t.Errorf("Expecting one request to each server, got %d and %d", srv.Requests(), eu.Requests())
+	}
+}
+
+func TestServer_SetConfigJSON(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetConfigJSON(sdkKey, `{"f":{"key":{"v":"raw","t":1,"p":[],"r":[],"i":""}}}`)
+	client := newTestClient(srv, configcat.ClientConfig{})
+	defer client.Close()
+	client.Refresh()
+
+	if value := client.GetStringValue("key", ""); value != "raw" {
+		t.Errorf("Expecting the value of the raw configuration, got %q", value)
+	}
+}
+
+func TestHandler_UnknownSDKKey(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := newTestClient(srv, configcat.ClientConfig{})
+	defer client.Close()
+
+	if status, _ := client.RefreshCtx(context.Background()); status != configcat.Failure {
+		t.Errorf("Expecting the refresh to fail for an unknown SDK key, got %v", status)
+	}
+}
+
+func TestHandler_SetFlagsValidates(t *testing.T) {
+	tests := map[string]*Flag{
+		"unsupported default": {Default: []string{}},
+		"rule type mismatch":  {Default: true, Rules: []Rule{{Comparator: configcat.OneOf, Value: "true"}}},
+		"unknown comparator":  {Default: true, Rules: []Rule{{Comparator: 18, Value: false}}},
+		"percentage sum":      {Default: 1, Percentages: []PercentageOption{{Percentage: 30, Value: 2}, {Percentage: 30, Value: 3}}},
+		"option type":         {Default: 1, Percentages: []PercentageOption{{Percentage: 100, Value: 2.0}}},
+		"nil flag":            nil,
+	}
+	for name, flag := range tests {
+		handler := &Handler{}
+		if err := handler.SetFlags(sdkKey, map[string]*Flag{"key": flag}); err == nil {
+			t.Errorf("%s: expecting an error", name)
+		}
+	}
+}
+
+func TestHandler_SetFlagsCopiesFlags(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	flags := map[string]*Flag{"key": {Default: "value", Rules: []Rule{{
+		ComparisonAttribute: "Email",
+		Comparator:          configcat.Contains,
+		ComparisonValue:     "@example.com",
+		Value:               "rule",
+	}}}}
+	if err := srv.SetFlags(sdkKey, flags); err != nil {
+		t.Fatal(err)
+	}
+	flags["key"].Default = "changed"
+	flags["key"].Rules[0].Value = "changed"
+	flags["added"] = &Flag{Default: true}
+	if err := srv.SetRedirect(sdkKey, srv.URL, configcat.NoRedirect); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(srv, configcat.ClientConfig{})
+	defer client.Close()
+	if _, err := client.RefreshCtx(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	user := configcat.NewUserWithAdditionalAttributes("id", "a@example.com", "", nil)
+	if value := client.GetStringValueForUser("key", "", user); value != "rule" {
+		t.Errorf("Expecting the flags as they were set, got %q", value)
+	}
+	if keys, _ := client.GetAllKeys(); len(keys) != 1 {
+		t.Errorf("Expecting only the flags as they were set, got %v", keys)
+	}
+}
@@ -1,17 +1,29 @@
 package configcat
 
 // FetchStatus describes the fetch response statuses.
-type fetchStatus int
+type FetchStatus int
 
 const (
 	// Fetched indicates that a new configuration was fetched.
-	Fetched fetchStatus = 0
+	Fetched FetchStatus = 0
 	// NotModified indicates that the current configuration is not modified.
-	NotModified fetchStatus = 1
+	NotModified FetchStatus = 1
 	// Failure indicates that the current configuration fetch is failed.
-	Failure fetchStatus = 2
+	Failure FetchStatus = 2
 )
 
+func (status FetchStatus) String() string {
+	switch status {
+	case Fetched:
+		return "fetched"
+	case NotModified:
+		return "not modified"
+	case Failure:
+		return "failure"
+	}
+	return "unknown"
+}
+
 // DataGovernance describes the location of your feature flag and setting data within the ConfigCat CDN.
 type DataGovernance int
 
@@ -22,6 +34,90 @@ const (
 	EuOnly DataGovernance = 1
 )
 
+// SettingType describes the type of a setting value as stored in the configuration.
+type SettingType int
+
+const (
+	// UnknownSetting is the type of the settings whose type is missing from the configuration.
+	// The values of such settings are served as they are stored, and the type is omitted
+	// when the setting is written back to JSON.
+	UnknownSetting SettingType = -1
+	BoolSetting    SettingType = 0
+	StringSetting  SettingType = 1
+	IntSetting     SettingType = 2
+	FloatSetting   SettingType = 3
+)
+
+func (kind SettingType) String() string {
+	switch kind {
+	case BoolSetting:
+		return "bool"
+	case StringSetting:
+		return "string"
+	case IntSetting:
+		return "int"
+	case FloatSetting:
+		return "float"
+	}
+	return "unknown"
+}
+
+// Comparator identifies the comparison operator of a targeting rule as stored in the configuration.
+// See https://configcat.com/docs/advanced/targeting.
+type Comparator int
+
+const (
+	OneOf              Comparator = 0
+	NotOneOf           Comparator = 1
+	Contains           Comparator = 2
+	NotContains        Comparator = 3
+	OneOfSemver        Comparator = 4
+	NotOneOfSemver     Comparator = 5
+	LessSemver         Comparator = 6
+	LessEqualSemver    Comparator = 7
+	GreaterSemver      Comparator = 8
+	GreaterEqualSemver Comparator = 9
+	EqualsNumber       Comparator = 10
+	NotEqualsNumber    Comparator = 11
+	LessNumber         Comparator = 12
+	LessEqualNumber    Comparator = 13
+	GreaterNumber      Comparator = 14
+	GreaterEqualNumber Comparator = 15
+	// OneOfSensitive compares the SHA1 hashes of the values, so the values aren't disclosed in the configuration.
+	OneOfSensitive Comparator = 16
+	// NotOneOfSensitive compares the SHA1 hashes of the values, so the values aren't disclosed in the configuration.
+	NotOneOfSensitive Comparator = 17
+)
+
+var comparatorTexts = []string{
+	"IS ONE OF",
+	"IS NOT ONE OF",
+	"CONTAINS",
+	"DOES NOT CONTAIN",
+	"IS ONE OF (SemVer)",
+	"IS NOT ONE OF (SemVer)",
+	"< (SemVer)",
+	"<= (SemVer)",
+	"> (SemVer)",
+	">= (SemVer)",
+	"= (Number)",
+	"<> (Number)",
+	"< (Number)",
+	"<= (Number)",
+	"> (Number)",
+	">= (Number)",
+	"IS ONE OF (Sensitive)",
+	"IS NOT ONE OF (Sensitive)",
+}
+
+// String returns the name of the comparator as displayed on the ConfigCat Dashboard, e.g. "IS ONE OF".
+func (comparator Comparator) String() string {
+	if comparator < OneOf || int(comparator) >= len(comparatorTexts) {
+		return "unknown"
+	}
+	return comparatorTexts[comparator]
+}
+
 const (
 	globalBaseUrl = "https://cdn-global.configcat.com"
 	euOnlyBaseUrl = "https://cdn-eu.configcat.com"
@@ -37,27 +133,3 @@ const (
 	pending   = 0
 	completed = 1
 )
-
-const (
-	entries     = "f"
-	preferences = "p"
-
-	preferencesUrl      = "u"
-	preferencesRedirect = "r"
-
-	settingValue                  = "v"
-	settingType                   = "t"
-	settingRolloutPercentageItems = "p"
-	settingRolloutRules           = "r"
-	settingVariationId            = "i"
-
-	rolloutValue               = "v"
-	rolloutComparisonAttribute = "a"
-	rolloutComparator          = "t"
-	rolloutComparisonValue     = "c"
-	rolloutVariationId         = "i"
-
-	percentageItemValue       = "v"
-	percentageItemPercentage  = "p"
-	percentageItemVariationId = "i"
-)
@@ -0,0 +1,31 @@
+package configcat
+
+import "time"
+
+// EvaluationDetails holds the result of a setting evaluation
+// together with the information about why that result was chosen.
+type EvaluationDetails struct {
+	// Key holds the key of the evaluated setting.
+	Key string
+	// Value holds the evaluated value, or the default value
+	// when the evaluation failed.
+	Value interface{}
+	// VariationID holds the variation ID of the evaluated value.
+	VariationID string
+	// User holds the user the setting was evaluated for.
+	User *User
+	// IsDefaultValue reports whether the default value was returned
+	// because the evaluation failed.
+	IsDefaultValue bool
+	// Error holds the reason of the failure when IsDefaultValue is true.
+	Error error
+	// FetchTime holds the time the configuration used for the evaluation
+	// was fetched. It's zero when the time is unknown.
+	FetchTime time.Time
+	// MatchedRule holds the targeting rule that decided the value,
+	// or nil if no targeting rule matched.
+	MatchedRule *RolloutRule
+	// MatchedPercentageOption holds the percentage option that decided the value,
+	// or nil if the value wasn't chosen by percentage options.
+	MatchedPercentageOption *PercentageOption
+}
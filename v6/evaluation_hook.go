@@ -0,0 +1,67 @@
+package configcat
+
+import "context"
+
+// EvaluationHook observes the setting evaluations of a Client, e.g. for tracing or analytics.
+// The hooks are called synchronously on the goroutine making the evaluation, so they
+// should return quickly and must be safe for concurrent use.
+//
+// The ctx passed to the hooks is the one given to the Ctx variants of the getters,
+// e.g. Client.GetValueCtx, and context.Background() otherwise. For the Variation ID
+// getters, defaultValue holds the default Variation ID.
+type EvaluationHook interface {
+	// BeforeEvaluation is called before the setting identified by key is evaluated.
+	BeforeEvaluation(ctx context.Context, key string, user *User, defaultValue interface{})
+	// AfterEvaluation is called after the setting was evaluated successfully.
+	AfterEvaluation(ctx context.Context, details EvaluationDetails, defaultValue interface{})
+	// EvaluationFailed is called in place of AfterEvaluation when the evaluation failed
+	// and the default value is returned. The reason of the failure is in details.Error.
+	EvaluationFailed(ctx context.Context, details EvaluationDetails, defaultValue interface{})
+}
+
+// EvaluationHookFuncs is an EvaluationHook calling the functions that are set.
+type EvaluationHookFuncs struct {
+	Before func(ctx context.Context, key string, user *User, defaultValue interface{})
+	After  func(ctx context.Context, details EvaluationDetails, defaultValue interface{})
+	Failed func(ctx context.Context, details EvaluationDetails, defaultValue interface{})
+}
+
+// BeforeEvaluation implements EvaluationHook.
+func (hook EvaluationHookFuncs) BeforeEvaluation(ctx context.Context, key string, user *User, defaultValue interface{}) {
+	if hook.Before != nil {
+		hook.Before(ctx, key, user, defaultValue)
+	}
+}
+
+// AfterEvaluation implements EvaluationHook.
+func (hook EvaluationHookFuncs) AfterEvaluation(ctx context.Context, details EvaluationDetails, defaultValue interface{}) {
+	if hook.After != nil {
+		hook.After(ctx, details, defaultValue)
+	}
+}
+
+// EvaluationFailed implements EvaluationHook.
+func (hook EvaluationHookFuncs) EvaluationFailed(ctx context.Context, details EvaluationDetails, defaultValue interface{}) {
+	if hook.Failed != nil {
+		hook.Failed(ctx, details, defaultValue)
+	}
+}
+
+// evaluationHooks calls a list of hooks in order.
+type evaluationHooks []EvaluationHook
+
+func (hooks evaluationHooks) before(ctx context.Context, key string, user *User, defaultValue interface{}) {
+	for _, hook := range hooks {
+		hook.BeforeEvaluation(ctx, key, user, defaultValue)
+	}
+}
+
+func (hooks evaluationHooks) after(ctx context.Context, details EvaluationDetails, defaultValue interface{}) {
+	for _, hook := range hooks {
+		if details.Error != nil {
+			hook.EvaluationFailed(ctx, details, defaultValue)
+		} else {
+			hook.AfterEvaluation(ctx, details, defaultValue)
+		}
+	}
+}
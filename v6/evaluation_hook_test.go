@@ -0,0 +1,166 @@
+package configcat
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type hookCall struct {
+	hook         string
+	key          string
+	details      EvaluationDetails
+	defaultValue interface{}
+}
+
+// recordingHook records the calls of an EvaluationHook.
+type recordingHook struct {
+	mu    sync.Mutex
+	calls []hookCall
+}
+
+func (hook *recordingHook) BeforeEvaluation(ctx context.Context, key string, user *User, defaultValue interface{}) {
+	hook.record(hookCall{hook: "before", key: key, defaultValue: defaultValue})
+}
+
+func (hook *recordingHook) AfterEvaluation(ctx context.Context, details EvaluationDetails, defaultValue interface{}) {
+	hook.record(hookCall{hook: "after", key: details.Key, details: details, defaultValue: defaultValue})
+}
+
+func (hook *recordingHook) EvaluationFailed(ctx context.Context, details EvaluationDetails, defaultValue interface{}) {
+	hook.record(hookCall{hook: "failed", key: details.Key, details: details, defaultValue: defaultValue})
+}
+
+func (hook *recordingHook) record(call hookCall) {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	hook.calls = append(hook.calls, call)
+}
+
+func (hook *recordingHook) takeCalls() []hookCall {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	calls := hook.calls
+	hook.calls = nil
+	return calls
+}
+
+func newHookTestClient(hooks ...EvaluationHook) *Client {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: detailsJson})
+	client := newInternal("fakeKey", ClientConfig{Mode: ManualPoll(), Hooks: hooks, Logger: DefaultLogger(LogLevelPanic)}, fetcher)
+	client.Refresh()
+	return client
+}
+
+func TestEvaluationHook_GetValue(t *testing.T) {
+	hook := &recordingHook{}
+	client := newHookTestClient(hook)
+	defer client.Close()
+
+	user := NewUserWithAdditionalAttributes("id", "a@example.com", "", nil)
+	client.GetValueForUser("key", "default", user)
+
+	calls := hook.takeCalls()
+	if len(calls) != 2 || calls[0].hook != "before" || calls[1].hook != "after" {
+		t.Fatalf("Expecting the hooks to be called before and after the evaluation, got %+v", calls)
+	}
+	details := calls[1].details
+	if details.Value != "ruleValue" || details.VariationID != "ruleId" || details.MatchedRule == nil || details.User != user {
+		t.Errorf("Unexpected evaluation details %+v", details)
+	}
+	if calls[0].defaultValue != "default" || calls[1].defaultValue != "default" {
+		t.Errorf("Expecting the default value to be passed to the hooks, got %+v", calls)
+	}
+}
+
+func TestEvaluationHook_Failures(t *testing.T) {
+	hook := &recordingHook{}
+	client := newHookTestClient(hook)
+	defer client.Close()
+
+	client.GetValue("missing", "default")
+	client.GetBoolValue("key", true)
+	client.GetVariationId("missing", "defaultId")
+
+	calls := hook.takeCalls()
+	if len(calls) != 6 {
+		t.Fatalf("Expecting 6 hook calls, got %+v", calls)
+	}
+	for _, i := range []int{1, 3, 5} {
+		if calls[i].hook != "failed" || calls[i].details.Error == nil || !calls[i].details.IsDefaultValue {
+			t.Errorf("Expecting the failure hook to be called with the error, got %+v", calls[i])
+		}
+	}
+	if calls[3].details.Value != true {
+		t.Errorf("Expecting the default value on a type mismatch, got %v", calls[3].details.Value)
+	}
+	if calls[5].details.VariationID != "defaultId" || calls[5].defaultValue != "defaultId" {
+		t.Errorf("Expecting the default Variation ID, got %+v", calls[5])
+	}
+}
+
+func TestEvaluationHook_PathsAndContext(t *testing.T) {
+	type ctxKey struct{}
+	var contexts []interface{}
+	var keys []string
+	hook := EvaluationHookFuncs{
+		After: func(ctx context.Context, details EvaluationDetails, defaultValue interface{}) {
+			contexts = append(contexts, ctx.Value(ctxKey{}))
+			keys = append(keys, details.Key)
+		},
+	}
+	client := newHookTestClient(hook)
+	defer client.Close()
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "traced")
+	client.GetValueCtx(ctx, "key", "", nil)
+	client.GetVariationIdCtx(ctx, "key", "", nil)
+	client.GetStringValue("key", "")
+	client.GetAllValues()
+	client.GetAllVariationIds()
+	client.Snapshot().GetValue("key", "")
+
+	if len(keys) != 6 {
+		t.Fatalf("Expecting every evaluation path to call the hooks, got %v", keys)
+	}
+	if contexts[0] != "traced" || contexts[1] != "traced" || contexts[2] != nil {
+		t.Errorf("Expecting the context of the Ctx getters to be passed to the hooks, got %v", contexts)
+	}
+}
+
+func TestEvaluationHook_Order(t *testing.T) {
+	var order []string
+	newHook := func(name string) EvaluationHook {
+		return EvaluationHookFuncs{
+			Before: func(ctx context.Context, key string, user *User, defaultValue interface{}) {
+				order = append(order, name+" before")
+			},
+			After: func(ctx context.Context, details EvaluationDetails, defaultValue interface{}) {
+				order = append(order, name+" after")
+			},
+		}
+	}
+	client := newHookTestClient(newHook("first"), newHook("second"))
+	defer client.Close()
+
+	client.GetValue("key", "")
+	expected := []string{"first before", "second before", "first after", "second after"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expecting the hooks to be called in order, got %v", order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expecting the hooks to be called in order, got %v", order)
+		}
+	}
+}
+
+func BenchmarkClient_GetValueWithHook(b *testing.B) {
+	client := newHookTestClient(EvaluationHookFuncs{})
+	defer client.Close()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		client.GetValue("key", "")
+	}
+}
@@ -1,33 +1,62 @@
 package configcat
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
 type fakeConfigProvider struct {
+	mu            sync.Mutex
 	result        fetchResponse
 	sleepDuration time.Duration
+	// lastETag holds the ETag sent with the last fetch.
+	lastETag string
+	// fetches holds the number of fetches.
+	fetches int
 }
 
 func newFakeConfigProvider() *fakeConfigProvider {
 	return &fakeConfigProvider{}
 }
 
-func (fetcher *fakeConfigProvider) getConfigurationAsync() *asyncResult {
+func (fetcher *fakeConfigProvider) getConfigurationAsync(ctx context.Context, eTag string) *asyncResult {
 	async := newAsyncResult()
+	fetcher.mu.Lock()
+	result, sleepDuration := fetcher.result, fetcher.sleepDuration
+	fetcher.lastETag = eTag
+	fetcher.fetches++
+	fetcher.mu.Unlock()
 	go func() {
-		if fetcher.sleepDuration > 0 {
-			time.Sleep(fetcher.sleepDuration)
+		if sleepDuration > 0 {
+			select {
+			case <-time.After(sleepDuration):
+			case <-ctx.Done():
+				async.complete(failedFetchResponse(0, ctx.Err()))
+				return
+			}
 		}
-		async.complete(fetcher.result)
+		async.complete(result)
 	}()
 
 	return async
 }
 
+func (fetcher *fakeConfigProvider) fetchCount() int {
+	fetcher.mu.Lock()
+	defer fetcher.mu.Unlock()
+	return fetcher.fetches
+}
+
 func (fetcher *fakeConfigProvider) SetResponse(response fetchResponse) {
+	fetcher.mu.Lock()
+	defer fetcher.mu.Unlock()
 	fetcher.result = response
 }
 
 func (fetcher *fakeConfigProvider) SetResponseWithDelay(response fetchResponse, delayDuration time.Duration) {
+	fetcher.mu.Lock()
+	defer fetcher.mu.Unlock()
 	fetcher.sleepDuration = delayDuration
 	fetcher.result = response
 }
@@ -1,9 +1,54 @@
 package configcat
 
+import (
+	"errors"
+	"fmt"
+)
+
 // fetchResponse represents a configuration fetch response.
 type fetchResponse struct {
-	status fetchStatus
-	body   string
+	status     FetchStatus
+	body       string
+	eTag       string
+	statusCode int
+	err        error
+}
+
+// FetchError describes why fetching the configuration failed.
+type FetchError struct {
+	// StatusCode holds the HTTP status code of the response,
+	// or 0 when no response was received.
+	StatusCode int
+	// Err holds the underlying error.
+	Err error
+}
+
+func (e *FetchError) Error() string {
+	if e.StatusCode == 0 {
+		return "config fetch failed: " + e.Err.Error()
+	}
+	return fmt.Sprintf("config fetch failed with status code %d: %v", e.StatusCode, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// failedFetchResponse creates a response describing a failed fetch.
+func failedFetchResponse(statusCode int, err error) fetchResponse {
+	return fetchResponse{status: Failure, statusCode: statusCode, err: &FetchError{StatusCode: statusCode, Err: err}}
+}
+
+// fetchErr returns the reason of the failure when the fetch is failed, otherwise nil.
+func (response fetchResponse) fetchErr() error {
+	if !response.isFailed() {
+		return nil
+	}
+	if response.err != nil {
+		return response.err
+	}
+	return &FetchError{StatusCode: response.statusCode, Err: errors.New("unknown error")}
 }
 
 // isFailed returns true if the fetch is failed, otherwise false.
@@ -0,0 +1,111 @@
+package configcat
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockRetryInterval is how often a file lock held by another process is tried again.
+const lockRetryInterval = 10 * time.Millisecond
+
+// FileCache is a ConfigCacheCtx which stores the configurations in files, so the processes
+// running on the same host can share them, and a new process can start with the configuration
+// fetched by an earlier one. The files are replaced atomically, so they can be read without
+// locking, and the writes are synchronized between the processes with file locks.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache creates a cache storing the configurations in files in the given directory,
+// which is created when it doesn't exist. When dir is empty, the configcat directory
+// of the system's temporary directory is used.
+func NewFileCache(dir string) *FileCache {
+	if len(dir) == 0 {
+		dir = filepath.Join(os.TempDir(), "configcat")
+	}
+	return &FileCache{dir: dir}
+}
+
+// Get reads the configuration from the file belonging to the key.
+// It returns an empty string when the file doesn't exist.
+func (cache *FileCache) Get(ctx context.Context, key string) (string, error) {
+	data, err := ioutil.ReadFile(cache.path(key))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Set writes the configuration into the file belonging to the key. The value is written
+// into a temporary file first, which then replaces the file, so the readers never see
+// a partially written configuration.
+func (cache *FileCache) Set(ctx context.Context, key string, value string) error {
+	if err := os.MkdirAll(cache.dir, 0755); err != nil {
+		return err
+	}
+
+	unlock, err := cache.lock(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := ioutil.TempFile(cache.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(value); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), cache.path(key))
+}
+
+func (cache *FileCache) path(key string) string {
+	return filepath.Join(cache.dir, key)
+}
+
+// lock acquires the exclusive lock of the key for writing. It waits until the lock
+// is acquired or ctx is done. The returned function releases the lock.
+func (cache *FileCache) lock(ctx context.Context, key string) (func(), error) {
+	file, err := os.OpenFile(cache.path(key)+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if locked {
+			return func() {
+				unlockFile(file)
+				file.Close()
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package configcat
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileCache_GetSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := NewFileCache(filepath.Join(dir, "cache"))
+	ctx := context.Background()
+
+	if value, err := cache.Get(ctx, "key"); value != "" || err != nil {
+		t.Errorf("Expecting an empty value for a missing key, got %q, %v", value, err)
+	}
+
+	if err := cache.Set(ctx, "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set(ctx, "key", "value2"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := cache.Get(ctx, "key"); value != "value2" || err != nil {
+		t.Errorf("Expecting the last value, got %q, %v", value, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "cache", "*.tmp"))
+	if len(files) != 0 {
+		t.Errorf("Expecting no temporary files to be left, got %v", files)
+	}
+}
+
+func TestFileCache_ConcurrentAccess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate instances, as if they were in separate processes.
+			cache := NewFileCache(dir)
+			value := fmt.Sprintf("value%d", i)
+			for j := 0; j < 20; j++ {
+				if err := cache.Set(ctx, "key", value); err != nil {
+					t.Error(err)
+				}
+				if read, err := cache.Get(ctx, "key"); err != nil || len(read) != len(value) {
+					t.Errorf("Expecting a complete value, got %q, %v", read, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestFileCache_GetDoesNotLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := NewFileCache(dir)
+	ctx := context.Background()
+	if err := ioutil.WriteFile(filepath.Join(dir, "key"), []byte("value"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := cache.Get(ctx, "key"); value != "value" || err != nil {
+		t.Errorf("Expecting the value, got %q, %v", value, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "key.lock")); !os.IsNotExist(err) {
+		t.Errorf("Expecting no lock file to be created by reading, got %v", err)
+	}
+
+	unlock, err := cache.lock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if value, err := NewFileCache(dir).Get(ctx, "key"); value != "value" || err != nil {
+		t.Errorf("Expecting the value while a writer holds the lock, got %q, %v", value, err)
+	}
+}
+
+func TestFileCache_LockHonoursContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := NewFileCache(dir)
+	unlock, err := cache.lock(context.Background(), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := NewFileCache(dir).Set(ctx, "key", "value"); err != context.DeadlineExceeded {
+		t.Errorf("Expecting the deadline to be exceeded while the file is locked, got %v", err)
+	}
+}
+
+func TestFileCache_SharedBetweenClients(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"value\"")})
+	client := newInternal("fakeKey", ClientConfig{Mode: ManualPoll(), CacheCtx: NewFileCache(dir)}, fetcher)
+	client.Refresh()
+	client.Close()
+
+	failing := newFakeConfigProvider()
+	failing.SetResponse(fetchResponse{status: Failure})
+	client = newInternal("fakeKey", ClientConfig{Mode: LazyLoad(time.Minute, false), CacheCtx: NewFileCache(dir)}, failing)
+	defer client.Close()
+
+	if result := client.GetValue("key", "default"); result != "value" {
+		t.Errorf("Expecting the value cached by the earlier client, got %v", result)
+	}
+}
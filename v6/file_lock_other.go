@@ -0,0 +1,18 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package configcat
+
+import (
+	"os"
+)
+
+// tryLockFile doesn't lock on this platform, the files are only replaced atomically.
+func tryLockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile releases the lock of the file.
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,48 @@
+//go:build windows
+// +build windows
+
+package configcat
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// tryLockFile tries to lock the file exclusively without blocking.
+// It returns false when the file is locked by another process.
+func tryLockFile(file *os.File) (bool, error) {
+	flags := uint32(lockfileFailImmediately | lockfileExclusiveLock)
+
+	var overlapped syscall.Overlapped
+	result, _, err := procLockFileEx.Call(file.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if result != 0 {
+		return true, nil
+	}
+	if err == errorLockViolation {
+		return false, nil
+	}
+	return false, err
+}
+
+// unlockFile releases the lock of the file.
+func unlockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	result, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if result == 0 {
+		return err
+	}
+	return nil
+}
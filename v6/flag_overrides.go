@@ -0,0 +1,367 @@
+package configcat
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverrideBehavior describes how the local flag overrides are combined with the remote configuration.
+type OverrideBehavior int
+
+const (
+	// LocalOnly uses only the local overrides; the remote configuration is never fetched.
+	LocalOnly OverrideBehavior = 0
+	// LocalOverRemote uses the local overrides in favour of the remote settings with the same key.
+	LocalOverRemote OverrideBehavior = 1
+	// RemoteOverLocal uses the remote settings in favour of the local overrides with the same key.
+	RemoteOverLocal OverrideBehavior = 2
+)
+
+// FlagOverrides describes the local feature flag and setting overrides.
+// The overrides can be given as values, read from a file, or both,
+// in which case the values take precedence over the file.
+type FlagOverrides struct {
+	// Behavior describes how the overrides are combined with the remote configuration.
+	Behavior OverrideBehavior
+	// Values holds the overridden values keyed by the setting keys.
+	// The values must be of type bool, string, int or float64.
+	Values map[string]interface{}
+	// FilePath is the path of a JSON file holding the overrides. The file is either in the
+	// format of the configuration downloaded from ConfigCat, or a simple key/value
+	// format like {"flags": {"key": value}}. When FilePath is a directory, all the
+	// .json files in it are read in the order of their names.
+	FilePath string
+	// ReloadInterval is how often FilePath is checked for modifications. When it's positive,
+	// the overrides are reloaded whenever the file is edited and the change listeners are notified.
+	ReloadInterval time.Duration
+}
+
+// localOverrides applies the flag overrides to the remote configurations.
+type localOverrides struct {
+	behavior OverrideBehavior
+	filePath string
+	logger   Logger
+	// values holds the settings built from FlagOverrides.Values.
+	values map[string]*Setting
+	// local holds the *config built from the overrides.
+	local atomic.Value
+	// merged holds the last *mergedConfig, so merging happens only when either side changes.
+	merged atomic.Value
+	// fileState identifies the version of the override file last read.
+	fileState string
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+type mergedConfig struct {
+	remote *config
+	local  *config
+	result *config
+}
+
+// simpleOverrides describes the simple key/value override file format.
+type simpleOverrides struct {
+	Flags map[string]interface{} `json:"flags"`
+}
+
+func newLocalOverrides(overrides *FlagOverrides, logger Logger) *localOverrides {
+	local := &localOverrides{
+		behavior: overrides.Behavior,
+		filePath: overrides.FilePath,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}
+
+	values, err := settingsFromValues(overrides.Values)
+	if err != nil {
+		logger.Errorf("Invalid flag override, %s", err)
+	}
+	local.values = values
+
+	conf, err := local.load()
+	if err != nil {
+		logger.Errorf("Reading the flag overrides from %s failed, %s", local.filePath, err)
+	}
+	local.local.Store(conf)
+	return local
+}
+
+// load builds the configuration from the override file and the values,
+// the values taking precedence. On failure the returned configuration
+// holds the settings that could be read. The version of the file is
+// recorded only when it was read successfully, so a failed read,
+// e.g. in the middle of an edit, is retried by the next reload.
+func (overrides *localOverrides) load() (*config, error) {
+	entries := map[string]*Setting{}
+	var err error
+	if len(overrides.filePath) > 0 {
+		state := overrideFileState(overrides.filePath)
+		var fileEntries map[string]*Setting
+		fileEntries, err = readOverridePath(overrides.filePath, overrides.logger)
+		for key, setting := range fileEntries {
+			entries[key] = setting
+		}
+		if err == nil {
+			overrides.fileState = state
+		}
+	}
+
+	for key, setting := range overrides.values {
+		entries[key] = setting
+	}
+	return &config{root: &Config{Settings: entries}}, err
+}
+
+// watch checks the override file for modifications at every interval, and reloads
+// the overrides when it was modified. The changed function is called with the
+// configurations built from the overrides before and after the reload.
+func (overrides *localOverrides) watch(interval time.Duration, changed func(oldLocal, newLocal *config)) {
+	overrides.logger.Debugf("Watching the flag overrides in %s with %+v interval.", overrides.filePath, interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-overrides.stop:
+				return
+			case <-ticker.C:
+				overrides.reload(changed)
+			}
+		}
+	}()
+}
+
+func (overrides *localOverrides) reload(changed func(oldLocal, newLocal *config)) {
+	if overrideFileState(overrides.filePath) == overrides.fileState {
+		return
+	}
+
+	conf, err := overrides.load()
+	if err != nil {
+		// Keep serving the previous overrides, the file might be in the middle of an edit.
+		overrides.logger.Errorf("Reloading the flag overrides from %s failed, %s", overrides.filePath, err)
+		return
+	}
+
+	old := overrides.local.Load().(*config)
+	if reflect.DeepEqual(old.root.Settings, conf.root.Settings) {
+		return
+	}
+
+	overrides.logger.Infof("Flag overrides reloaded from %s.", overrides.filePath)
+	overrides.local.Store(conf)
+	changed(old, conf)
+}
+
+// close stops watching the override file.
+func (overrides *localOverrides) close() {
+	overrides.stopOnce.Do(func() {
+		close(overrides.stop)
+	})
+}
+
+// apply returns the configuration to evaluate against in place of the remote one.
+func (overrides *localOverrides) apply(remote *config) *config {
+	local := overrides.local.Load().(*config)
+	if overrides.behavior == LocalOnly || remote == nil {
+		return local
+	}
+
+	if last, ok := overrides.merged.Load().(*mergedConfig); ok && last.remote == remote && last.local == local {
+		return last.result
+	}
+
+	result := overrides.merge(remote, local)
+	overrides.merged.Store(&mergedConfig{remote: remote, local: local, result: result})
+	return result
+}
+
+// merge combines the remote configuration with the configuration built from the overrides.
+func (overrides *localOverrides) merge(remote *config, local *config) *config {
+	if overrides.behavior == LocalOnly || remote == nil {
+		return local
+	}
+
+	entries := make(map[string]*Setting, len(remote.root.Settings)+len(local.root.Settings))
+	primary, secondary := local, remote
+	if overrides.behavior == RemoteOverLocal {
+		primary, secondary = remote, local
+	}
+	for key, setting := range secondary.root.Settings {
+		entries[key] = setting
+	}
+	for key, setting := range primary.root.Settings {
+		entries[key] = setting
+	}
+
+	return &config{
+		jsonBody:  remote.jsonBody,
+		root:      &Config{Settings: entries, Preferences: remote.root.Preferences},
+		fetchTime: remote.fetchTime,
+		eTag:      remote.eTag,
+	}
+}
+
+// overrideFileState identifies the current version of an override file or directory
+// by the modification times, sizes and content hashes of the files, so an edit keeping
+// the size within the resolution of the modification times is noticed too.
+// It's empty when the path can't be read.
+func overrideFileState(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("%d:%d:%s", info.ModTime().UnixNano(), info.Size(), fileHash(path))
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return ""
+	}
+	var state strings.Builder
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		fmt.Fprintf(&state, "%s:%d:%d:%s;", file.Name(), file.ModTime().UnixNano(), file.Size(),
+			fileHash(filepath.Join(path, file.Name())))
+	}
+	return state.String()
+}
+
+// fileHash returns the SHA-1 hash of the content of the file, or an empty string when it can't be read.
+func fileHash(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	hash := sha1.Sum(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// readOverridePath reads the settings from an override file, or from all the
+// override files of a directory, the later files taking precedence.
+func readOverridePath(path string, logger Logger) (map[string]*Setting, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readOverrideFile(path, logger)
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]*Setting{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		fileEntries, err := readOverrideFile(filepath.Join(path, file.Name()), logger)
+		if err != nil {
+			return entries, fmt.Errorf("%s: %v", file.Name(), err)
+		}
+		for key, setting := range fileEntries {
+			entries[key] = setting
+		}
+	}
+	return entries, nil
+}
+
+// readOverrideFile reads the settings from an override file. The values of unsupported
+// types in the simple format are skipped with a warning, both at the start and on reloads.
+func readOverrideFile(path string, logger Logger) (map[string]*Setting, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The numbers are decoded as json.Number, so whole numbers can be told apart from decimal ones.
+	var simple simpleOverrides
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&simple); err != nil {
+		return nil, err
+	}
+	if simple.Flags != nil {
+		entries, err := settingsFromValues(simple.Flags)
+		if err != nil {
+			logger.Warnf("Skipping an invalid flag override in %s, %s", path, err)
+		}
+		return entries, nil
+	}
+
+	conf, err := parseConfig(string(data), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return conf.root.Settings, nil
+}
+
+// settingsFromValues creates settings serving the given values. Values of unsupported
+// types are skipped and reported in the returned error.
+func settingsFromValues(values map[string]interface{}) (map[string]*Setting, error) {
+	entries := make(map[string]*Setting, len(values))
+	var err error
+	for key, value := range values {
+		setting, ok := settingFromValue(value)
+		if !ok {
+			err = fmt.Errorf("unsupported value %v (%T) for key %s", value, value, key)
+			continue
+		}
+		entries[key] = setting
+	}
+	return entries, err
+}
+
+// settingFromValue creates a setting serving the given value in the
+// same representation as if it was decoded from the configuration JSON.
+func settingFromValue(value interface{}) (*Setting, bool) {
+	switch v := value.(type) {
+	case bool:
+		return &Setting{Value: v, Type: BoolSetting}, true
+	case string:
+		return &Setting{Value: v, Type: StringSetting}, true
+	case int:
+		return &Setting{Value: float64(v), Type: IntSetting}, true
+	case int32:
+		return &Setting{Value: float64(v), Type: IntSetting}, true
+	case int64:
+		return &Setting{Value: float64(v), Type: IntSetting}, true
+	case float32:
+		return &Setting{Value: float64(v), Type: FloatSetting}, true
+	case float64:
+		return &Setting{Value: v, Type: FloatSetting}, true
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return settingFromValue(i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return settingFromValue(f)
+		}
+	}
+	return nil, false
+}
+
+// localOnlyProvider is a configProvider used with LocalOnly overrides which never fetches.
+type localOnlyProvider struct{}
+
+func (provider localOnlyProvider) getConfigurationAsync(ctx context.Context, eTag string) *asyncResult {
+	return asCompletedAsyncResult(fetchResponse{status: NotModified})
+}
@@ -0,0 +1,292 @@
+package configcat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newOverrideTestClient(overrides *FlagOverrides) (*fakeConfigProvider, *Client) {
+	fetcher := newFakeConfigProvider()
+	client := newInternal("fakeKey", ClientConfig{Mode: ManualPoll(), FlagOverrides: overrides}, fetcher)
+	return fetcher, client
+}
+
+func writeOverrideFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFlagOverrides_LocalOnly_Values(t *testing.T) {
+	fetcher, client := newOverrideTestClient(&FlagOverrides{
+		Behavior: LocalOnly,
+		Values:   map[string]interface{}{"bool": true, "string": "local", "int": 42, "float": 3.14},
+	})
+	defer client.Close()
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "string", "\"remote\"")})
+	client.Refresh()
+
+	if !client.GetBoolValue("bool", false) {
+		t.Error("Expecting the overridden bool value")
+	}
+	if value := client.GetStringValue("string", ""); value != "local" {
+		t.Errorf("Expecting the overridden string value, got %v", value)
+	}
+	if value := client.GetIntValue("int", 0); value != 42 {
+		t.Errorf("Expecting the overridden int value, got %v", value)
+	}
+	if value := client.GetFloatValue("float", 0); value != 3.14 {
+		t.Errorf("Expecting the overridden float value, got %v", value)
+	}
+}
+
+func TestFlagOverrides_LocalOverRemote(t *testing.T) {
+	fetcher, client := newOverrideTestClient(&FlagOverrides{
+		Behavior: LocalOverRemote,
+		Values:   map[string]interface{}{"key": "local", "localOnly": "local"},
+	})
+	defer client.Close()
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: "{ \"f\": { \"key\": { \"v\": \"remote\" }, \"remoteOnly\": { \"v\": \"remote\" }}}"})
+	client.Refresh()
+
+	values := client.GetAllValues()
+	expected := map[string]interface{}{"key": "local", "localOnly": "local", "remoteOnly": "remote"}
+	if fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Errorf("Expecting %v, got %v", expected, values)
+	}
+}
+
+func TestFlagOverrides_RemoteOverLocal(t *testing.T) {
+	fetcher, client := newOverrideTestClient(&FlagOverrides{
+		Behavior: RemoteOverLocal,
+		Values:   map[string]interface{}{"key": "local", "localOnly": "local"},
+	})
+	defer client.Close()
+
+	if value := client.GetValue("key", ""); value != "local" {
+		t.Errorf("Expecting the local value without a remote configuration, got %v", value)
+	}
+
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: "{ \"f\": { \"key\": { \"v\": \"remote\" }, \"remoteOnly\": { \"v\": \"remote\" }}}"})
+	client.Refresh()
+
+	values := client.GetAllValues()
+	expected := map[string]interface{}{"key": "remote", "localOnly": "local", "remoteOnly": "remote"}
+	if fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Errorf("Expecting %v, got %v", expected, values)
+	}
+}
+
+func TestFlagOverrides_SimpleFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeOverrideFile(t, dir, "overrides.json", "{\"flags\": {\"enabled\": true, \"count\": 5, \"name\": \"file\"}}")
+	_, client := newOverrideTestClient(&FlagOverrides{
+		Behavior: LocalOnly,
+		FilePath: path,
+		Values:   map[string]interface{}{"name": "value"},
+	})
+	defer client.Close()
+
+	if !client.GetBoolValue("enabled", false) {
+		t.Error("Expecting the bool value from the file")
+	}
+	if value := client.GetIntValue("count", 0); value != 5 {
+		t.Errorf("Expecting the int value from the file, got %v", value)
+	}
+	if value := client.GetStringValue("name", ""); value != "value" {
+		t.Errorf("Expecting Values to take precedence over the file, got %v", value)
+	}
+}
+
+func TestFlagOverrides_ConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeOverrideFile(t, dir, "config_v5.json", detailsJson)
+	_, client := newOverrideTestClient(&FlagOverrides{Behavior: LocalOnly, FilePath: path})
+	defer client.Close()
+
+	if value := client.GetValueForUser("key", "", NewUserWithAdditionalAttributes("id", "a@example.com", "", nil)); value != "ruleValue" {
+		t.Errorf("Expecting the targeting rules of the file to be evaluated, got %v", value)
+	}
+}
+
+func TestFlagOverrides_Directory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeOverrideFile(t, dir, "a.json", "{\"flags\": {\"first\": \"a\", \"second\": \"a\"}}")
+	writeOverrideFile(t, dir, "b.json", "{\"flags\": {\"second\": \"b\"}}")
+	writeOverrideFile(t, dir, "ignored.txt", "{\"flags\": {\"first\": \"ignored\"}}")
+	_, client := newOverrideTestClient(&FlagOverrides{Behavior: LocalOnly, FilePath: dir})
+	defer client.Close()
+
+	if value := client.GetValue("first", ""); value != "a" {
+		t.Errorf("Expecting the value of a.json, got %v", value)
+	}
+	if value := client.GetValue("second", ""); value != "b" {
+		t.Errorf("Expecting the value of b.json, got %v", value)
+	}
+}
+
+func TestFlagOverrides_ReloadsModifiedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeOverrideFile(t, dir, "overrides.json", "{\"flags\": {\"enabled\": false, \"name\": \"file\"}}")
+	_, client := newOverrideTestClient(&FlagOverrides{
+		Behavior:       LocalOnly,
+		FilePath:       path,
+		ReloadInterval: 10 * time.Millisecond,
+	})
+	defer client.Close()
+
+	changes := make(chan *ConfigChange, 1)
+	client.Subscribe(func(change *ConfigChange) {
+		changes <- change
+	})
+
+	if client.GetBoolValue("enabled", true) {
+		t.Fatal("Expecting the initial value from the file")
+	}
+
+	writeOverrideFile(t, dir, "overrides.json", "{\"flags\": {\"enabled\": true, \"name\": \"file\", \"added\": 1}}")
+
+	select {
+	case change := <-changes:
+		if !reflect.DeepEqual(change.Added, []string{"added"}) || !reflect.DeepEqual(change.Modified, []string{"enabled"}) {
+			t.Errorf("Unexpected change %+v", change)
+		}
+		if change.Old.GetValue("enabled", nil) != false || change.New.GetValue("enabled", nil) != true {
+			t.Errorf("Unexpected snapshots of the change %+v", change)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expecting a change notification after the file was modified")
+	}
+
+	if !client.GetBoolValue("enabled", false) {
+		t.Error("Expecting the reloaded value")
+	}
+}
+
+func TestFlagOverrides_RetriesFailedReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The broken and the fixed content have the same size and modification time,
+	// like an edit finished within the resolution of a coarse filesystem clock.
+	modTime := time.Now().Add(-time.Hour)
+	path := writeOverrideFile(t, dir, "overrides.json", `{"flags":{"a":1}}`)
+	local := newLocalOverrides(&FlagOverrides{Behavior: LocalOnly, FilePath: path}, DefaultLogger(LogLevelPanic))
+	writeOverrideFile(t, dir, "overrides.json", `{"flags":{"a":222`)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	changed := 0
+	local.reload(func(oldLocal, newLocal *config) { changed++ })
+
+	writeOverrideFile(t, dir, "overrides.json", `{"flags":{"a":2}}`)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	local.reload(func(oldLocal, newLocal *config) { changed++ })
+
+	if conf := local.local.Load().(*config); changed != 1 || fmt.Sprint(conf.root.Settings["a"].Value) != "2" {
+		t.Errorf("Expecting the fixed file to be reloaded, got %d changes and %v", changed, conf.root.Settings["a"].Value)
+	}
+}
+
+func TestFlagOverrides_ReloadsEditKeepingSizeAndModTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	modTime := time.Now().Add(-time.Hour)
+	path := writeOverrideFile(t, dir, "overrides.json", `{"flags":{"v":1}}`)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	local := newLocalOverrides(&FlagOverrides{Behavior: LocalOnly, FilePath: path}, DefaultLogger(LogLevelPanic))
+
+	writeOverrideFile(t, dir, "overrides.json", `{"flags":{"v":2}}`)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	changed := 0
+	local.reload(func(oldLocal, newLocal *config) { changed++ })
+
+	if conf := local.local.Load().(*config); changed != 1 || fmt.Sprint(conf.root.Settings["v"].Value) != "2" {
+		t.Errorf("Expecting the edited file to be reloaded, got %d changes and %v", changed, conf.root.Settings["v"].Value)
+	}
+}
+
+func TestFlagOverrides_SkipsUnsupportedValuesOnReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeOverrideFile(t, dir, "overrides.json", `{"flags": {"enabled": true, "bad": [1]}}`)
+	local := newLocalOverrides(&FlagOverrides{Behavior: LocalOnly, FilePath: path}, DefaultLogger(LogLevelPanic))
+	if conf := local.local.Load().(*config); conf.root.Settings["enabled"] == nil || conf.root.Settings["bad"] != nil {
+		t.Fatalf("Expecting only the supported values to be loaded, got %v", conf.root.Settings)
+	}
+
+	writeOverrideFile(t, dir, "overrides.json", `{"flags": {"enabled": false, "bad": [1]}}`)
+	changed := 0
+	local.reload(func(oldLocal, newLocal *config) { changed++ })
+	local.reload(func(oldLocal, newLocal *config) { changed++ })
+
+	if conf := local.local.Load().(*config); changed != 1 || conf.root.Settings["enabled"].Value != false {
+		t.Errorf("Expecting the supported values to be reloaded once, got %d changes and %v", changed, conf.root.Settings)
+	}
+}
+
+func TestFlagOverrides_KeepsOverridesOnInvalidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeOverrideFile(t, dir, "overrides.json", "{\"flags\": {\"name\": \"file\"}}")
+	overrides := newLocalOverrides(&FlagOverrides{Behavior: LocalOnly, FilePath: path}, DefaultLogger(LogLevelWarn))
+
+	writeOverrideFile(t, dir, "overrides.json", "{\"flags\": {\"name\": ")
+	overrides.reload(func(oldLocal, newLocal *config) {
+		t.Error("Unexpected change for an invalid file")
+	})
+
+	if value := overrides.apply(nil).root.Settings["name"].Value; value != "file" {
+		t.Errorf("Expecting the previous overrides to be kept, got %v", value)
+	}
+}
@@ -1,29 +1,38 @@
 package configcat
 
 import (
+	"context"
+	"math"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
 // lazyLoadingPolicy describes a refreshPolicy which uses an expiring cache to maintain the internally stored configuration.
 type lazyLoadingPolicy struct {
-	configRefresher
-	cacheInterval   time.Duration
-	isFetching      uint32
-	initialized     uint32
-	useAsyncRefresh bool
-	lastRefreshTime time.Time
-	fetching        *asyncResult
-	init            *async
+	*configRefresher
+	cacheInterval time.Duration
+	// maxStale is the age up to which an expired configuration is served while it's refreshed in the background.
+	maxStale     time.Duration
+	fetchTimeout time.Duration
+	isFetching   uint32
+	// fetchMu guards fetching.
+	fetchMu sync.Mutex
+	// fetching holds the result of the last fetch.
+	fetching *asyncResult
 }
 
-// lazyLoadConfig describes the configuration for auto polling.
+// lazyLoadConfig describes the configuration for lazy loading.
 type lazyLoadConfig struct {
 	// The cache invalidation interval.
 	cacheInterval time.Duration
 	// If you use the asynchronous refresh then when a request is being made on the cache while it's expired,
 	// the previous value will be returned immediately until the fetching of the new configuration is completed
 	useAsyncRefresh bool
+	// The age up to which an expired configuration is served while it's refreshed in the background.
+	maxStale time.Duration
+	// The maximum time to wait for a fetch when the configuration is older than maxStale.
+	fetchTimeout time.Duration
 }
 
 func (config lazyLoadConfig) getModeIdentifier() string {
@@ -39,87 +48,106 @@ func LazyLoad(cacheInterval time.Duration, useAsyncRefresh bool) RefreshMode {
 	return lazyLoadConfig{cacheInterval: cacheInterval, useAsyncRefresh: useAsyncRefresh}
 }
 
+// LazyLoadWithMaxStale creates a lazy loading refresh mode with stale-while-revalidate semantics.
+// A configuration younger than cacheInterval is served directly. An older one is served while it's
+// refreshed in the background, until it gets older than maxStale. Then the getters block until
+// the fetch completes, or at most for fetchTimeout when it's positive, after which the stale
+// configuration is served.
+func LazyLoadWithMaxStale(cacheInterval time.Duration, maxStale time.Duration, fetchTimeout time.Duration) RefreshMode {
+	return lazyLoadConfig{cacheInterval: cacheInterval, maxStale: maxStale, fetchTimeout: fetchTimeout}
+}
+
 // newLazyLoadingPolicy initializes a new lazyLoadingPolicy.
 func newLazyLoadingPolicy(
-	configFetcher configProvider,
-	cache ConfigCache,
-	logger Logger,
-	sdkKey string,
+	refresher *configRefresher,
 	config lazyLoadConfig) *lazyLoadingPolicy {
-	return &lazyLoadingPolicy{configRefresher: newConfigRefresher(configFetcher, cache, logger, sdkKey),
-		cacheInterval:   config.cacheInterval,
-		isFetching:      no,
-		initialized:     no,
-		useAsyncRefresh: config.useAsyncRefresh,
-		lastRefreshTime: time.Time{},
-		init:            newAsync()}
+	maxStale := config.maxStale
+	if config.useAsyncRefresh {
+		maxStale = math.MaxInt64
+	} else if maxStale < config.cacheInterval {
+		maxStale = config.cacheInterval
+	}
+
+	return &lazyLoadingPolicy{configRefresher: refresher,
+		cacheInterval: config.cacheInterval,
+		maxStale:      maxStale,
+		fetchTimeout:  config.fetchTimeout,
+		isFetching:    no}
 }
 
 // getConfigurationAsync reads the current configuration value.
 func (policy *lazyLoadingPolicy) getConfigurationAsync() *asyncResult {
-	if time.Since(policy.lastRefreshTime) > policy.cacheInterval {
-		initialized := policy.init.isCompleted()
-
-		if initialized && !atomic.CompareAndSwapUint32(&policy.isFetching, no, yes) {
-			if policy.useAsyncRefresh {
-				return policy.readCache()
-			}
-			return policy.fetching
-		}
+	// The fetch time is read from the cache, so a configuration cached
+	// by an earlier process is used until the cache interval expires.
+	cached := policy.get(context.Background())
+	if policy.isOffline() {
+		return asCompletedAsyncResult(cached)
+	}
 
-		policy.logger.Debugln("Cache expired, refreshing.")
-		if initialized {
-			policy.fetching = policy.fetch()
-			if policy.useAsyncRefresh {
-				return policy.readCache()
-			}
-			return policy.fetching
+	var age time.Duration
+	if cached != nil {
+		age = policy.clock.Now().Sub(cached.fetchTime)
+		if age <= policy.cacheInterval {
+			return asCompletedAsyncResult(cached)
 		}
+	}
 
-		if atomic.CompareAndSwapUint32(&policy.isFetching, no, yes) {
-			policy.fetching = policy.fetch()
-		}
-		return policy.init.apply(func() interface{} {
-			return policy.get()
-		})
+	if policy.inBackoff() {
+		policy.logger.Debugln("Backing off after failed fetches, serving the cached configuration.")
+		return asCompletedAsyncResult(cached)
 	}
 
-	return policy.readCache()
+	policy.logger.Debugln("Cache expired, refreshing.")
+	fetching := policy.startFetch()
+	if cached != nil && age <= policy.maxStale {
+		return asCompletedAsyncResult(cached)
+	}
+	return policy.waitForFetch(fetching, cached)
 }
 
 // close shuts down the policy.
 func (policy *lazyLoadingPolicy) close() {
 }
 
-func (policy *lazyLoadingPolicy) fetch() *asyncResult {
-	return policy.configFetcher.getConfigurationAsync().applyThen(func(result interface{}) interface{} {
-		defer atomic.StoreUint32(&policy.isFetching, no)
-
-		response := result.(fetchResponse)
-		cached := policy.get()
-		fetched := response.isFetched()
+// startFetch starts a fetch unless one is already in progress,
+// and returns the result of the fetch in progress.
+func (policy *lazyLoadingPolicy) startFetch() *asyncResult {
+	policy.fetchMu.Lock()
+	defer policy.fetchMu.Unlock()
+	if atomic.CompareAndSwapUint32(&policy.isFetching, no, yes) {
+		policy.fetching = policy.fetch()
+	}
+	return policy.fetching
+}
 
-		if fetched && response.body != cached {
-			policy.set(response.body)
-		}
+// waitForFetch returns the result of the fetch, or the stale configuration
+// when the fetch doesn't complete within fetchTimeout.
+func (policy *lazyLoadingPolicy) waitForFetch(fetching *asyncResult, stale *config) *asyncResult {
+	if policy.fetchTimeout <= 0 {
+		return fetching
+	}
 
-		if !response.isFailed() {
-			policy.lastRefreshTime = time.Now()
+	result := newAsyncResult()
+	go func() {
+		select {
+		case <-fetching.done:
+			result.complete(fetching.result)
+		case <-policy.clock.After(policy.fetchTimeout):
+			policy.logger.Warnf("Fetching the configuration did not complete within %v, serving the stale configuration.", policy.fetchTimeout)
+			result.complete(stale)
 		}
+	}()
+	return result
+}
 
-		if atomic.CompareAndSwapUint32(&policy.initialized, no, yes) {
-			policy.init.complete()
-		}
+func (policy *lazyLoadingPolicy) fetch() *asyncResult {
+	return policy.fetchAsync(context.Background()).applyThen(func(result interface{}) interface{} {
+		defer atomic.StoreUint32(&policy.isFetching, no)
 
-		if fetched {
-			return response.body
+		cached, _ := policy.update(context.Background(), result.(fetchResponse))
+		if cached == nil {
+			cached = policy.get(context.Background())
 		}
-
 		return cached
 	})
 }
-
-func (policy *lazyLoadingPolicy) readCache() *asyncResult {
-	policy.logger.Debugln("Reading from cache.")
-	return asCompletedAsyncResult(policy.get())
-}
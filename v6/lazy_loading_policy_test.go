@@ -1,6 +1,7 @@
 package configcat
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -8,29 +9,28 @@ import (
 func TestLazyLoadingPolicy_GetConfigurationAsync_DoNotUseAsync(t *testing.T) {
 	fetcher := newFakeConfigProvider()
 
-	fetcher.SetResponse(fetchResponse{status: Fetched, body: "test"})
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test")})
 	logger := DefaultLogger(LogLevelWarn)
-	policy := newLazyLoadingPolicy(
-		fetcher,
-		newInMemoryConfigCache(),
-		logger,
-		"",
-		lazyLoadConfig{time.Second * 2, false})
-	config := policy.getConfigurationAsync().get().(string)
+	clock := NewManualClock(time.Now())
+	refresher := newConfigRefresher(fetcher, NewInMemoryCache(), logger, "")
+	refresher.clock = clock
+	policy := newLazyLoadingPolicy(refresher, lazyLoadConfig{cacheInterval: time.Second * 2, useAsyncRefresh: false})
+	config := configValue(policy.getConfigurationAsync().get())
 
 	if config != "test" {
 		t.Error("Expecting test as result")
 	}
 
-	fetcher.SetResponse(fetchResponse{status: Fetched, body: "test2"})
-	config = policy.getConfigurationAsync().get().(string)
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test2")})
+	clock.Advance(time.Second)
+	config = configValue(policy.getConfigurationAsync().get())
 
 	if config != "test" {
 		t.Error("Expecting test as result")
 	}
 
-	time.Sleep(time.Second * 2)
-	config = policy.getConfigurationAsync().get().(string)
+	clock.Advance(time.Second * 2)
+	config = configValue(policy.getConfigurationAsync().get())
 
 	if config != "test2" {
 		t.Error("Expecting test2 as result")
@@ -43,12 +43,9 @@ func TestLazyLoadingPolicy_GetConfigurationAsync_Fail(t *testing.T) {
 	fetcher.SetResponse(fetchResponse{status: Failure, body: ""})
 	logger := DefaultLogger(LogLevelWarn)
 	policy := newLazyLoadingPolicy(
-		fetcher,
-		newInMemoryConfigCache(),
-		logger,
-		"",
-		lazyLoadConfig{time.Second * 2, false})
-	config := policy.getConfigurationAsync().get().(string)
+		newConfigRefresher(fetcher, NewInMemoryCache(), logger, ""),
+		lazyLoadConfig{cacheInterval: time.Second * 2, useAsyncRefresh: false})
+	config := configValue(policy.getConfigurationAsync().get())
 
 	if config != "" {
 		t.Error("Expecting default")
@@ -58,33 +55,94 @@ func TestLazyLoadingPolicy_GetConfigurationAsync_Fail(t *testing.T) {
 func TestLazyLoadingPolicy_GetConfigurationAsync_UseAsync(t *testing.T) {
 	fetcher := newFakeConfigProvider()
 
-	fetcher.SetResponse(fetchResponse{status: Fetched, body: "test"})
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test")})
 	logger := DefaultLogger(LogLevelWarn)
-	policy := newLazyLoadingPolicy(
-		fetcher,
-		newInMemoryConfigCache(),
-		logger,
-		"",
-		lazyLoadConfig{time.Second * 2, true})
-	config := policy.getConfigurationAsync().get().(string)
+	clock := NewManualClock(time.Now())
+	refresher := newConfigRefresher(fetcher, NewInMemoryCache(), logger, "")
+	refresher.clock = clock
+	policy := newLazyLoadingPolicy(refresher, lazyLoadConfig{cacheInterval: time.Second * 2, useAsyncRefresh: true})
+	config := configValue(policy.getConfigurationAsync().get())
 
 	if config != "test" {
 		t.Error("Expecting test as result")
 	}
 
-	time.Sleep(time.Second * 2)
+	clock.Advance(time.Second * 3)
 
-	fetcher.SetResponseWithDelay(fetchResponse{status: Fetched, body: "test2"}, time.Second*1)
-	config = policy.getConfigurationAsync().get().(string)
+	fetcher.SetResponseWithDelay(fetchResponse{status: Fetched, body: testConfigJson("test2")}, time.Millisecond*100)
+	config = configValue(policy.getConfigurationAsync().get())
 
 	if config != "test" {
 		t.Error("Expecting test as result")
 	}
 
-	time.Sleep(time.Second * 2)
-	config = policy.getConfigurationAsync().get().(string)
+	time.Sleep(time.Millisecond * 300)
+	config = configValue(policy.getConfigurationAsync().get())
 
 	if config != "test2" {
 		t.Error("Expecting test2 as result")
 	}
 }
+
+func TestLazyLoadingPolicy_UsesFreshCacheOfEarlierProcess(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("fetched")})
+	cache := NewInMemoryCache()
+	refresher := newConfigRefresher(fetcher, cache, DefaultLogger(LogLevelWarn), "")
+	cached := &config{jsonBody: testConfigJson("cached"), fetchTime: time.Now()}
+	cache.Set(context.Background(), refresher.cacheKey, cached.cacheEntry())
+
+	policy := newLazyLoadingPolicy(refresher, lazyLoadConfig{cacheInterval: time.Minute, useAsyncRefresh: false})
+	config := configValue(policy.getConfigurationAsync().get())
+
+	if config != "cached" {
+		t.Errorf("Expecting the fresh cached configuration, got %q", config)
+	}
+}
+
+func newMaxStaleTestPolicy(fetcher *fakeConfigProvider, cachedAge time.Duration, fetchTimeout time.Duration) *lazyLoadingPolicy {
+	cache := NewInMemoryCache()
+	refresher := newConfigRefresher(fetcher, cache, DefaultLogger(LogLevelError), "")
+	cached := &config{jsonBody: testConfigJson("cached"), fetchTime: time.Now().Add(-cachedAge)}
+	cache.Set(context.Background(), refresher.cacheKey, cached.cacheEntry())
+	return newLazyLoadingPolicy(refresher, lazyLoadConfig{cacheInterval: time.Minute, maxStale: time.Hour, fetchTimeout: fetchTimeout})
+}
+
+func TestLazyLoadingPolicy_MaxStale_ServesStaleWhileRefreshing(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponseWithDelay(fetchResponse{status: Fetched, body: testConfigJson("fetched")}, 100*time.Millisecond)
+	policy := newMaxStaleTestPolicy(fetcher, 10*time.Minute, 0)
+
+	if config := configValue(policy.getConfigurationAsync().get()); config != "cached" {
+		t.Errorf("Expecting the stale configuration, got %q", config)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if config := configValue(policy.getConfigurationAsync().get()); config != "fetched" {
+		t.Errorf("Expecting the configuration refreshed in the background, got %q", config)
+	}
+}
+
+func TestLazyLoadingPolicy_MaxStale_BlocksWhenTooStale(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponseWithDelay(fetchResponse{status: Fetched, body: testConfigJson("fetched")}, 100*time.Millisecond)
+	policy := newMaxStaleTestPolicy(fetcher, 2*time.Hour, time.Second)
+
+	if config := configValue(policy.getConfigurationAsync().get()); config != "fetched" {
+		t.Errorf("Expecting the fetched configuration, got %q", config)
+	}
+}
+
+func TestLazyLoadingPolicy_MaxStale_ServesStaleAfterFetchTimeout(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponseWithDelay(fetchResponse{status: Fetched, body: testConfigJson("fetched")}, time.Second)
+	policy := newMaxStaleTestPolicy(fetcher, 2*time.Hour, 50*time.Millisecond)
+
+	start := time.Now()
+	if config := configValue(policy.getConfigurationAsync().get()); config != "cached" {
+		t.Errorf("Expecting the stale configuration after the timeout, got %q", config)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expecting the fetch timeout to be honoured, waited %v", elapsed)
+	}
+}
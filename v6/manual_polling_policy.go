@@ -1,8 +1,10 @@
 package configcat
 
+import "context"
+
 // manualPollingPolicy describes a refreshPolicy which fetches the latest configuration over HTTP every time when a get configuration is called.
 type manualPollingPolicy struct {
-	configRefresher
+	*configRefresher
 }
 
 type manualPollConfig struct {
@@ -22,18 +24,13 @@ func ManualPoll() RefreshMode {
 }
 
 // newManualPollingPolicy initializes a new manualPollingPolicy.
-func newManualPollingPolicy(
-	configFetcher configProvider,
-	cache ConfigCache,
-	logger Logger,
-	sdkKey string) *manualPollingPolicy {
-
-	return &manualPollingPolicy{configRefresher: newConfigRefresher(configFetcher, cache, logger, sdkKey)}
+func newManualPollingPolicy(refresher *configRefresher) *manualPollingPolicy {
+	return &manualPollingPolicy{configRefresher: refresher}
 }
 
 // getConfigurationAsync reads the current configuration value.
 func (policy *manualPollingPolicy) getConfigurationAsync() *asyncResult {
-	return asCompletedAsyncResult(policy.get())
+	return asCompletedAsyncResult(policy.get(context.Background()))
 }
 
 // close shuts down the policy.
@@ -1,30 +1,28 @@
 package configcat
 
 import (
+	"context"
 	"testing"
 )
 
 func TestManualPollingPolicy_GetConfigurationAsync(t *testing.T) {
 	fetcher := newFakeConfigProvider()
 	logger := DefaultLogger(LogLevelWarn)
-	fetcher.SetResponse(fetchResponse{status: Fetched, body: "test"})
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test")})
 	policy := newManualPollingPolicy(
-		fetcher,
-		newInMemoryConfigCache(),
-		logger,
-		"",
+		newConfigRefresher(fetcher, NewInMemoryCache(), logger, ""),
 	)
 
-	policy.refreshAsync().wait()
-	config := policy.getConfigurationAsync().get().(string)
+	policy.refreshAsync(context.Background()).wait()
+	config := configValue(policy.getConfigurationAsync().get())
 
 	if config != "test" {
 		t.Error("Expecting test as result")
 	}
 
-	fetcher.SetResponse(fetchResponse{status: Fetched, body: "test2"})
-	policy.refreshAsync().wait()
-	config = policy.getConfigurationAsync().get().(string)
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test2")})
+	policy.refreshAsync(context.Background()).wait()
+	config = configValue(policy.getConfigurationAsync().get())
 
 	if config != "test2" {
 		t.Error("Expecting test2 as result")
@@ -36,14 +34,36 @@ func TestManualPollingPolicy_GetConfigurationAsync_Fail(t *testing.T) {
 	logger := DefaultLogger(LogLevelWarn)
 	fetcher.SetResponse(fetchResponse{status: Failure, body: ""})
 	policy := newManualPollingPolicy(
-		fetcher,
-		newInMemoryConfigCache(),
-		logger,
-		"",
+		newConfigRefresher(fetcher, NewInMemoryCache(), logger, ""),
 	)
-	config := policy.getConfigurationAsync().get().(string)
+	config := configValue(policy.getConfigurationAsync().get())
 
 	if config != "" {
 		t.Error("Expecting default")
 	}
 }
+
+func TestManualPollingPolicy_CacheEntrySurvivesRestart(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	logger := DefaultLogger(LogLevelWarn)
+	cache := NewInMemoryCache()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: testConfigJson("test"), eTag: "etag1"})
+	policy := newManualPollingPolicy(newConfigRefresher(fetcher, cache, logger, ""))
+	policy.refreshAsync(context.Background()).wait()
+	fetchTime := policy.getLastCachedConfig().fetchTime
+
+	restarted := newManualPollingPolicy(newConfigRefresher(fetcher, cache, logger, ""))
+	conf := restarted.getConfigurationAsync().get().(*config)
+	if configValue(conf) != "test" || conf.eTag != "etag1" || !conf.fetchTime.Equal(fetchTime) {
+		t.Errorf("Expecting the cached configuration with its ETag and fetch time, got %+v", conf)
+	}
+
+	fetcher.SetResponse(fetchResponse{status: NotModified})
+	restarted.refreshAsync(context.Background()).wait()
+	if fetcher.lastETag != "etag1" {
+		t.Errorf("Expecting the cached ETag to be sent, got %q", fetcher.lastETag)
+	}
+	if conf := restarted.getLastCachedConfig(); configValue(conf) != "test" || conf.fetchTime.Before(fetchTime) {
+		t.Errorf("Expecting the fetch time to be renewed, got %+v", conf)
+	}
+}
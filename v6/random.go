@@ -0,0 +1,38 @@
+package configcat
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand is a random number generator which is safe for concurrent use.
+type lockedRand struct {
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+// newLockedRand creates a random number generator using the given source,
+// or a source seeded with the current time when it's nil.
+func newLockedRand(source rand.Source) *lockedRand {
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	return &lockedRand{random: rand.New(source)}
+}
+
+// float64 returns a random number in [0.0, 1.0).
+func (random *lockedRand) float64() float64 {
+	random.mu.Lock()
+	defer random.mu.Unlock()
+	return random.random.Float64()
+}
+
+// jitter randomizes the duration by the given fraction of it, so for
+// example with 0.5, 10s becomes a random duration between 5s and 15s.
+func (random *lockedRand) jitter(duration time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return duration
+	}
+	return time.Duration(float64(duration) * (1 + fraction*(2*random.float64()-1)))
+}
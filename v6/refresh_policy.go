@@ -1,30 +1,65 @@
 package configcat
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
-	CacheBase = "go_"+ ConfigJsonName +"_%s"
+	// CacheBase is the format of the cache keys, filled with the SHA-1 hash of the SDK key.
+	// The v2 segment tells the entries holding the fetch time and the ETag apart from the
+	// ones of earlier SDK versions, which can't read them, so the two can share a cache.
+	CacheBase = "go_" + ConfigJsonName + "_v2_%s"
 )
 
+// cacheWriteTimeout bounds the cache writes, which aren't cancelled with the operation they're made for.
+const cacheWriteTimeout = 30 * time.Second
+
 type refreshPolicy interface {
 	getConfigurationAsync() *asyncResult
-	getLastCachedConfig() string
-	refreshAsync() *async
+	getLastCachedConfig() *config
+	// refreshAsync fetches the configuration and stores it when a new one was fetched.
+	// The result of the returned asyncResult is a fetchResponse describing the outcome.
+	refreshAsync(ctx context.Context) *asyncResult
+	// setOffline switches between the offline mode, where no HTTP requests
+	// are made and the configuration is served from the cache, and the online mode.
+	setOffline(offline bool)
+	isOffline() bool
 	close()
 }
 
 type configRefresher struct {
 	configFetcher configProvider
-	cache         ConfigCache
+	cache         ConfigCacheCtx
 	logger        Logger
-	inMemoryValue string
+	// inMemoryValue holds the last known *config.
+	inMemoryValue atomic.Value
 	cacheKey      string
-	sync.RWMutex
+	// changeNotifier is called when set stores a configuration
+	// with a content different from the previous one.
+	changeNotifier func(oldConfig, newConfig *config)
+	// offline is yes when no HTTP requests should be made.
+	offline uint32
+	// backoff tracks the failed fetches when the fetches are delayed after failures, otherwise it's nil.
+	backoff *backoffState
+	// random is the source of randomness of the jitters.
+	random *lockedRand
+	clock  Clock
+	// mu guards the updates of inMemoryValue and version. It's never
+	// held during cache I/O, so a slow cache doesn't block the readers.
+	mu sync.Mutex
+	// version is incremented whenever store updates inMemoryValue.
+	version uint64
+	// written holds the version whose cache write succeeded last. While it's behind version,
+	// the cache may hold an older entry, so the readers keep to inMemoryValue.
+	written uint64
+	// writeMu serializes the cache writes, so they happen in the order of the updates.
+	writeMu sync.Mutex
 }
 
 type RefreshMode interface {
@@ -32,47 +67,203 @@ type RefreshMode interface {
 	accept(visitor pollingModeVisitor) refreshPolicy
 }
 
-func newConfigRefresher(configFetcher configProvider, cache ConfigCache, logger Logger, sdkKey string) configRefresher {
+func newConfigRefresher(configFetcher configProvider, cache ConfigCacheCtx, logger Logger, sdkKey string) *configRefresher {
 	sha := sha1.New()
 	sha.Write([]byte(sdkKey))
 	hash := hex.EncodeToString(sha.Sum(nil))
 	cacheKey := fmt.Sprintf(CacheBase, hash)
-	return configRefresher{configFetcher: configFetcher, cache: cache, logger: logger, cacheKey: cacheKey}
+	return &configRefresher{configFetcher: configFetcher, cache: cache, logger: logger, cacheKey: cacheKey, random: newLockedRand(nil), clock: realClock{}}
 }
 
-func (refresher *configRefresher) refreshAsync() *async {
-	return refresher.configFetcher.getConfigurationAsync().accept(func(result interface{}) {
+func (refresher *configRefresher) refreshAsync(ctx context.Context) *asyncResult {
+	if refresher.isOffline() {
+		refresher.logger.Warnf("The client is in offline mode, the refresh is skipped.")
+		return asCompletedAsyncResult(fetchResponse{status: NotModified})
+	}
+
+	return refresher.fetchAsync(ctx).applyThen(func(result interface{}) interface{} {
 		response := result.(fetchResponse)
-		if result.(fetchResponse).isFetched() {
-			refresher.set(response.body)
+		if _, err := refresher.update(ctx, response); err != nil {
+			return failedFetchResponse(response.statusCode, err)
 		}
+		return response
 	})
 }
 
-func (refresher *configRefresher) getLastCachedConfig() string {
-	return refresher.inMemoryValue
+// fetchAsync fetches the configuration, sending the ETag of the cached one
+// so the server can respond with 304 Not Modified.
+func (refresher *configRefresher) fetchAsync(ctx context.Context) *asyncResult {
+	var eTag string
+	if cached := refresher.get(ctx); cached != nil {
+		eTag = cached.eTag
+	}
+	return refresher.configFetcher.getConfigurationAsync(ctx, eTag)
+}
+
+// update stores the outcome of a fetch and returns the current configuration.
+// A fetched configuration replaces the cached one, and a not modified response
+// renews the fetch time of the cached one. It returns an error when the fetched
+// configuration is not a valid configuration JSON.
+func (refresher *configRefresher) update(ctx context.Context, response fetchResponse) (*config, error) {
+	if refresher.backoff != nil {
+		refresher.backoff.record(response)
+	}
+
+	switch {
+	case response.isFetched():
+		return refresher.set(ctx, response.body, response.eTag)
+	case response.isNotModified():
+		if cached := refresher.get(ctx); cached != nil {
+			return refresher.store(ctx, cached.withFetchTime(refresher.fetchTimeNow())), nil
+		}
+	}
+	return refresher.get(ctx), nil
+}
+
+// fetchTimeNow returns the current time in the precision the fetch times are cached with.
+func (refresher *configRefresher) fetchTimeNow() time.Time {
+	return refresher.clock.Now().Truncate(time.Millisecond)
+}
+
+// inBackoff reports whether the fetches are delayed because of earlier failures.
+func (refresher *configRefresher) inBackoff() bool {
+	return refresher.backoff != nil && refresher.backoff.active()
+}
+
+func (refresher *configRefresher) setOffline(offline bool) {
+	value := uint32(no)
+	if offline {
+		value = yes
+	}
+	atomic.StoreUint32(&refresher.offline, value)
 }
 
-// get reads the configuration.
-func (refresher *configRefresher) get() string {
-	refresher.RLock()
-	defer refresher.RUnlock()
-	value, err := refresher.cache.Get(refresher.cacheKey)
+func (refresher *configRefresher) isOffline() bool {
+	return atomic.LoadUint32(&refresher.offline) == yes
+}
+
+func (refresher *configRefresher) getLastCachedConfig() *config {
+	conf, _ := refresher.inMemoryValue.Load().(*config)
+	return conf
+}
+
+// get reads the configuration. The cached JSON is only parsed
+// when it differs from the last known configuration.
+func (refresher *configRefresher) get(ctx context.Context) *config {
+	refresher.mu.Lock()
+	version := refresher.version
+	pending := refresher.written != version
+	refresher.mu.Unlock()
+	if pending {
+		return refresher.getLastCachedConfig()
+	}
+
+	value, err := refresher.cache.Get(ctx, refresher.cacheKey)
 	if err != nil {
 		refresher.logger.Errorf("Reading from the cache failed, %s", err)
-		return refresher.inMemoryValue
+		return refresher.getLastCachedConfig()
+	}
+
+	if len(value) == 0 {
+		return refresher.getLastCachedConfig()
+	}
+
+	current := refresher.getLastCachedConfig()
+	fetchTime, eTag, jsonBody, err := parseCacheEntry(value)
+	if err != nil {
+		refresher.logger.Errorf("Reading the cached configuration failed, %s", err)
+		return current
 	}
 
-	return value
+	var conf *config
+	if current != nil && current.jsonBody == jsonBody {
+		if current.eTag == eTag && current.fetchTime.Equal(fetchTime) {
+			return current
+		}
+		conf = current.withFetchTime(fetchTime)
+	} else {
+		conf, err = parseConfig(jsonBody, fetchTime)
+		if err != nil {
+			refresher.logger.Errorf("Parsing the cached configuration failed, %s", err)
+			return current
+		}
+	}
+	conf.eTag = eTag
+
+	refresher.mu.Lock()
+	defer refresher.mu.Unlock()
+	if refresher.version != version || refresher.written != version {
+		// A newer configuration was stored while reading the cache.
+		return refresher.getLastCachedConfig()
+	}
+	refresher.inMemoryValue.Store(conf)
+	return conf
 }
 
-// set writes the configuration.
-func (refresher *configRefresher) set(value string) {
-	refresher.Lock()
-	defer refresher.Unlock()
-	refresher.inMemoryValue = value
-	err := refresher.cache.Set(refresher.cacheKey, value)
+// set parses and writes the configuration fetched with the given ETag. It returns the parsed
+// configuration, or an error when the value is not a valid configuration JSON.
+func (refresher *configRefresher) set(ctx context.Context, value string, eTag string) (*config, error) {
+	conf, err := parseConfig(value, refresher.fetchTimeNow())
+	if err != nil {
+		refresher.logger.Errorf("Parsing the fetched configuration failed, %s", err)
+		return nil, err
+	}
+	conf.eTag = eTag
+
+	refresher.store(ctx, conf)
+	return conf, nil
+}
+
+// store writes the configuration into the cache, and notifies
+// about the change when its content differs from the previous one.
+// When the cache write fails, the configuration is served from memory
+// until a later write succeeds.
+func (refresher *configRefresher) store(ctx context.Context, conf *config) *config {
+	refresher.writeMu.Lock()
+	refresher.mu.Lock()
+	old := refresher.getLastCachedConfig()
+	refresher.inMemoryValue.Store(conf)
+	refresher.version++
+	version := refresher.version
+	refresher.mu.Unlock()
+
+	// The fetched configuration is kept even when the caller gives up right after the fetch.
+	writeCtx, cancel := context.WithTimeout(detachedContext{ctx}, cacheWriteTimeout)
+	err := refresher.cache.Set(writeCtx, refresher.cacheKey, conf.cacheEntry())
+	cancel()
+	if err == nil {
+		refresher.mu.Lock()
+		refresher.written = version
+		refresher.mu.Unlock()
+	}
+	refresher.writeMu.Unlock()
 	if err != nil {
 		refresher.logger.Errorf("Saving into the cache failed, %s", err)
 	}
+
+	if refresher.changeNotifier != nil && (old == nil || old.jsonBody != conf.jsonBody) {
+		refresher.changeNotifier(old, conf)
+	}
+	return conf
+}
+
+// detachedContext carries the values of its parent, but not its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
 }
@@ -7,24 +7,60 @@ type pollingModeVisitor interface {
 }
 
 type refreshPolicyFactory struct {
-	configFetcher configProvider
-	cache         ConfigCache
-	logger        Logger
-	sdkKey        string
+	configFetcher  configProvider
+	cache          ConfigCacheCtx
+	logger         Logger
+	sdkKey         string
+	changeNotifier func(oldConfig, newConfig *config)
+	// offline is the initial offline mode of the policies.
+	offline bool
+	// backoff describes how the fetches are delayed after failures, or nil when they aren't.
+	backoff *Backoff
+	// random is the source of randomness of the jitters.
+	random *lockedRand
+	// clock tells the time to the policies, or it's nil for the wall time.
+	clock Clock
 }
 
-func newRefreshPolicyFactory(configFetcher configProvider, cache ConfigCache, logger Logger, sdkKey string) *refreshPolicyFactory {
-	return &refreshPolicyFactory{configFetcher: configFetcher, cache: cache, logger: logger, sdkKey: sdkKey}
+func newRefreshPolicyFactory(
+	configFetcher configProvider,
+	cache ConfigCacheCtx,
+	logger Logger,
+	sdkKey string,
+	changeNotifier func(oldConfig, newConfig *config)) *refreshPolicyFactory {
+	return &refreshPolicyFactory{
+		configFetcher:  configFetcher,
+		cache:          cache,
+		logger:         logger,
+		sdkKey:         sdkKey,
+		changeNotifier: changeNotifier,
+	}
 }
 
 func (factory *refreshPolicyFactory) visitAutoPoll(config autoPollConfig) refreshPolicy {
-	return newAutoPollingPolicy(factory.configFetcher, factory.cache, factory.logger, factory.sdkKey, config)
+	return newAutoPollingPolicy(factory.newConfigRefresher(), config)
 }
 
 func (factory *refreshPolicyFactory) visitManualPoll(config manualPollConfig) refreshPolicy {
-	return newManualPollingPolicy(factory.configFetcher, factory.cache, factory.logger, factory.sdkKey)
+	return newManualPollingPolicy(factory.newConfigRefresher())
 }
 
 func (factory *refreshPolicyFactory) visitLazyLoad(config lazyLoadConfig) refreshPolicy {
-	return newLazyLoadingPolicy(factory.configFetcher, factory.cache, factory.logger, factory.sdkKey, config)
+	return newLazyLoadingPolicy(factory.newConfigRefresher(), config)
+}
+
+func (factory *refreshPolicyFactory) newConfigRefresher() *configRefresher {
+	refresher := newConfigRefresher(factory.configFetcher, factory.cache, factory.logger, factory.sdkKey)
+	refresher.changeNotifier = factory.changeNotifier
+	refresher.setOffline(factory.offline)
+	if factory.random != nil {
+		refresher.random = factory.random
+	}
+	if factory.clock != nil {
+		refresher.clock = factory.clock
+	}
+	if factory.backoff != nil {
+		refresher.backoff = newBackoffState(*factory.backoff, refresher.random, refresher.clock)
+	}
+	return refresher
 }
@@ -10,229 +10,184 @@ import (
 )
 
 type rolloutEvaluator struct {
-	logger          Logger
-	comparatorTexts []string
+	logger Logger
 }
 
 func newRolloutEvaluator(logger Logger) *rolloutEvaluator {
-	return &rolloutEvaluator{logger: logger,
-		comparatorTexts: []string{
-			"IS ONE OF",
-			"IS NOT ONE OF",
-			"CONTAINS",
-			"DOES NOT CONTAIN",
-			"IS ONE OF (SemVer)",
-			"IS NOT ONE OF (SemVer)",
-			"< (SemVer)",
-			"<= (SemVer)",
-			"> (SemVer)",
-			">= (SemVer)",
-			"= (Number)",
-			"<> (Number)",
-			"< (Number)",
-			"<= (Number)",
-			"> (Number)",
-			">= (Number)",
-			"IS ONE OF (Sensitive)",
-			"IS NOT ONE OF (Sensitive)",
-		}}
+	return &rolloutEvaluator{logger: logger}
 }
 
-func (evaluator *rolloutEvaluator) evaluate(json interface{}, key string, user *User) (interface{}, string) {
-
-	node, ok := json.(map[string]interface{})
-	if !ok {
-		return nil, ""
-	}
-
+// evaluate returns the value and variation ID of the setting for the given user,
+// along with the targeting rule or percentage option that was matched, if any.
+func (evaluator *rolloutEvaluator) evaluate(setting *Setting, key string, user *User) (interface{}, string, *RolloutRule, *PercentageOption) {
 	evaluator.logger.Infof("Evaluating GetValue(%s).", key)
 
-	rolloutRules, rolloutOk := node[settingRolloutRules].([]interface{})
-	percentageRules, percentageOk := node[settingRolloutPercentageItems].([]interface{})
-
 	if user == nil {
-		if (rolloutOk && len(rolloutRules) > 0) || (percentageOk && len(percentageRules) > 0) {
+		if len(setting.RolloutRules) > 0 || len(setting.PercentageOptions) > 0 {
 			evaluator.logger.Warnln("Evaluating GetValue(" + key + "). UserObject missing! You should pass a " +
 				"UserObject to GetValueForUser() in order to make targeting work properly. " +
 				"Read more: https://configcat.com/docs/advanced/user-object.")
 		}
 
-		result := node[settingValue]
-		evaluator.logger.Infof("Returning %v.", result)
-		return result, evaluator.extractVariationId(node[settingVariationId])
+		evaluator.logger.Infof("Returning %v.", setting.Value)
+		return setting.Value, setting.VariationID, nil, nil
 	}
 
 	evaluator.logger.Infof("User object: %v", user)
 
-	if rolloutOk {
-		for _, r := range rolloutRules {
-			rule, ok := r.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			comparisonAttribute, ok := rule[rolloutComparisonAttribute].(string)
-			comparisonValue, ok := rule[rolloutComparisonValue].(string)
-			comparator, ok := rule[rolloutComparator].(float64)
-			variationId, ok := rule[rolloutVariationId].(string)
-			userValue := user.GetAttribute(comparisonAttribute)
-			value := rule[rolloutValue]
-
-			if !ok || len(userValue) == 0 {
-				evaluator.logNoMatch(comparisonAttribute, userValue, comparator, comparisonValue)
-				continue
-			}
+	for _, rule := range setting.RolloutRules {
+		comparisonAttribute := rule.ComparisonAttribute
+		comparisonValue := rule.ComparisonValue
+		comparator := rule.Comparator
+		variationId := rule.VariationID
+		userValue := user.GetAttribute(comparisonAttribute)
+		value := rule.Value
 
-			switch comparator {
-			//IS ONE OF
-			case 0:
-				separated := strings.Split(comparisonValue, ",")
-				for _, item := range separated {
-					if strings.Contains(strings.TrimSpace(item), userValue) {
-						evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-						return value, variationId
-					}
-				}
-			//IS NOT ONE OF
-			case 1:
-				separated := strings.Split(comparisonValue, ",")
-				found := false
-				for _, item := range separated {
-					if strings.Contains(strings.TrimSpace(item), userValue) {
-						found = true
-					}
-				}
+		if len(userValue) == 0 {
+			evaluator.logNoMatch(comparisonAttribute, userValue, comparator, comparisonValue)
+			continue
+		}
 
-				if !found {
+		switch comparator {
+		case OneOf:
+			separated := strings.Split(comparisonValue, ",")
+			for _, item := range separated {
+				if strings.Contains(strings.TrimSpace(item), userValue) {
 					evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-					return value, variationId
-				}
-			//CONTAINS
-			case 2:
-				if strings.Contains(userValue, comparisonValue) {
-					evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-					return value, variationId
-				}
-			//DOES NOT CONTAIN
-			case 3:
-				if !strings.Contains(userValue, comparisonValue) {
-					evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-					return value, variationId
-				}
-			//IS ONE OF, IS NOT ONE OF (SemVer)
-			case 4, 5:
-				separated := strings.Split(comparisonValue, ",")
-				userVersion, err := semver.Make(userValue)
-				if err != nil {
-					evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
-					continue
+					return value, variationId, rule, nil
 				}
-				matched := false
-				shouldContinue := false
-				for _, item := range separated {
-					cmpItem := strings.TrimSpace(item)
-					if len(cmpItem) == 0 {
-						continue
-					}
-
-					semVer, err := semver.Make(cmpItem)
-					if err != nil {
-						evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
-						shouldContinue = true
-						break
-					}
-
-					matched = userVersion.EQ(semVer) || matched
+			}
+		case NotOneOf:
+			separated := strings.Split(comparisonValue, ",")
+			found := false
+			for _, item := range separated {
+				if strings.Contains(strings.TrimSpace(item), userValue) {
+					found = true
 				}
+			}
 
-				if shouldContinue {
+			if !found {
+				evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
+				return value, variationId, rule, nil
+			}
+		case Contains:
+			if strings.Contains(userValue, comparisonValue) {
+				evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
+				return value, variationId, rule, nil
+			}
+		case NotContains:
+			if !strings.Contains(userValue, comparisonValue) {
+				evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
+				return value, variationId, rule, nil
+			}
+		case OneOfSemver, NotOneOfSemver:
+			separated := strings.Split(comparisonValue, ",")
+			userVersion, err := semver.Make(userValue)
+			if err != nil {
+				evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
+				continue
+			}
+			matched := false
+			shouldContinue := false
+			for _, item := range separated {
+				cmpItem := strings.TrimSpace(item)
+				if len(cmpItem) == 0 {
 					continue
 				}
 
-				if (matched && comparator == 4) || (!matched && comparator == 5) {
-					evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-					return value, variationId
-				}
-			//LESS THAN, LESS THAN OR EQUALS TO, GREATER THAN, GREATER THAN OR EQUALS TO (SemVer)
-			case 6, 7, 8, 9:
-				userVersion, err := semver.Make(userValue)
+				semVer, err := semver.Make(cmpItem)
 				if err != nil {
 					evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
-					continue
+					shouldContinue = true
+					break
 				}
 
-				cmpVersion, err := semver.Make(strings.TrimSpace(comparisonValue))
-				if err != nil {
-					evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
-					continue
-				}
+				matched = userVersion.EQ(semVer) || matched
+			}
 
-				if (comparator == 6 && userVersion.LT(cmpVersion)) ||
-					(comparator == 7 && userVersion.LTE(cmpVersion)) ||
-					(comparator == 8 && userVersion.GT(cmpVersion)) ||
-					(comparator == 9 && userVersion.GTE(cmpVersion)) {
-					evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-					return value, variationId
-				}
-			//LESS THAN, LESS THAN OR EQUALS TO, GREATER THAN, GREATER THAN OR EQUALS TO (SemVer)
-			case 10, 11, 12, 13, 14, 15:
-				userDouble, err := strconv.ParseFloat(strings.Replace(userValue, ",", ".", -1), 64)
-				if err != nil {
-					evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
-					continue
-				}
+			if shouldContinue {
+				continue
+			}
 
-				cmpDouble, err := strconv.ParseFloat(strings.Replace(comparisonValue, ",", ".", -1), 64)
-				if err != nil {
-					evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
-					continue
-				}
+			if (matched && comparator == OneOfSemver) || (!matched && comparator == NotOneOfSemver) {
+				evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
+				return value, variationId, rule, nil
+			}
+		case LessSemver, LessEqualSemver, GreaterSemver, GreaterEqualSemver:
+			userVersion, err := semver.Make(userValue)
+			if err != nil {
+				evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
+				continue
+			}
 
-				if (comparator == 10 && userDouble == cmpDouble) ||
-					(comparator == 11 && userDouble != cmpDouble) ||
-					(comparator == 12 && userDouble < cmpDouble) ||
-					(comparator == 13 && userDouble <= cmpDouble) ||
-					(comparator == 14 && userDouble > cmpDouble) ||
-					(comparator == 15 && userDouble >= cmpDouble) {
-					evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-					return value, variationId
-				}
-			//IS ONE OF (Sensitive)
-			case 16:
-				separated := strings.Split(comparisonValue, ",")
-				sha := sha1.New()
-				sha.Write([]byte(userValue))
-				hash := hex.EncodeToString(sha.Sum(nil))
-				for _, item := range separated {
-					if strings.Contains(strings.TrimSpace(item), hash) {
-						evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-						return value, variationId
-					}
-				}
-			//IS NOT ONE OF (Sensitive)
-			case 17:
-				separated := strings.Split(comparisonValue, ",")
-				found := false
-				sha := sha1.New()
-				sha.Write([]byte(userValue))
-				hash := hex.EncodeToString(sha.Sum(nil))
-				for _, item := range separated {
-					if strings.Contains(strings.TrimSpace(item), hash) {
-						found = true
-					}
-				}
+			cmpVersion, err := semver.Make(strings.TrimSpace(comparisonValue))
+			if err != nil {
+				evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
+				continue
+			}
+
+			if (comparator == LessSemver && userVersion.LT(cmpVersion)) ||
+				(comparator == LessEqualSemver && userVersion.LTE(cmpVersion)) ||
+				(comparator == GreaterSemver && userVersion.GT(cmpVersion)) ||
+				(comparator == GreaterEqualSemver && userVersion.GTE(cmpVersion)) {
+				evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
+				return value, variationId, rule, nil
+			}
+		case EqualsNumber, NotEqualsNumber, LessNumber, LessEqualNumber, GreaterNumber, GreaterEqualNumber:
+			userDouble, err := strconv.ParseFloat(strings.Replace(userValue, ",", ".", -1), 64)
+			if err != nil {
+				evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
+				continue
+			}
+
+			cmpDouble, err := strconv.ParseFloat(strings.Replace(comparisonValue, ",", ".", -1), 64)
+			if err != nil {
+				evaluator.logFormatError(comparisonAttribute, userValue, comparator, comparisonValue, err.Error())
+				continue
+			}
 
-				if !found {
+			if (comparator == EqualsNumber && userDouble == cmpDouble) ||
+				(comparator == NotEqualsNumber && userDouble != cmpDouble) ||
+				(comparator == LessNumber && userDouble < cmpDouble) ||
+				(comparator == LessEqualNumber && userDouble <= cmpDouble) ||
+				(comparator == GreaterNumber && userDouble > cmpDouble) ||
+				(comparator == GreaterEqualNumber && userDouble >= cmpDouble) {
+				evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
+				return value, variationId, rule, nil
+			}
+		case OneOfSensitive:
+			separated := strings.Split(comparisonValue, ",")
+			sha := sha1.New()
+			sha.Write([]byte(userValue))
+			hash := hex.EncodeToString(sha.Sum(nil))
+			for _, item := range separated {
+				if strings.Contains(strings.TrimSpace(item), hash) {
 					evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
-					return value, variationId
+					return value, variationId, rule, nil
+				}
+			}
+		case NotOneOfSensitive:
+			separated := strings.Split(comparisonValue, ",")
+			found := false
+			sha := sha1.New()
+			sha.Write([]byte(userValue))
+			hash := hex.EncodeToString(sha.Sum(nil))
+			for _, item := range separated {
+				if strings.Contains(strings.TrimSpace(item), hash) {
+					found = true
 				}
 			}
 
-			evaluator.logNoMatch(comparisonAttribute, userValue, comparator, comparisonValue)
+			if !found {
+				evaluator.logMatch(comparisonAttribute, userValue, comparator, comparisonValue, value)
+				return value, variationId, rule, nil
+			}
 		}
+
+		evaluator.logNoMatch(comparisonAttribute, userValue, comparator, comparisonValue)
 	}
 
-	if percentageOk && len(percentageRules) > 0 {
+	if len(setting.PercentageOptions) > 0 {
 		hashCandidate := key + user.identifier
 		sha := sha1.New()
 		sha.Write([]byte(hashCandidate))
@@ -241,51 +196,34 @@ func (evaluator *rolloutEvaluator) evaluate(json interface{}, key string, user *
 		scaled := num % 100
 		if err == nil {
 			bucket := int64(0)
-			for _, r := range percentageRules {
-				rule, ok := r.(map[string]interface{})
-				if ok {
-					p, ok := rule[percentageItemPercentage].(float64)
-					if ok {
-						percentage := int64(p)
-						bucket += percentage
-						if scaled < bucket {
-							result := rule[percentageItemValue]
-							evaluator.logger.Infof("Evaluating %% options. Returning %s", result)
-							return result, evaluator.extractVariationId(rule[percentageItemVariationId])
-						}
-					}
+			for _, rule := range setting.PercentageOptions {
+				bucket += rule.Percentage
+				if scaled < bucket {
+					evaluator.logger.Infof("Evaluating %% options. Returning %s", rule.Value)
+					return rule.Value, rule.VariationID, nil, rule
 				}
 			}
 		}
 	}
 
-	result := node[settingValue]
-	evaluator.logger.Infof("Returning %v.", result)
-	return result, evaluator.extractVariationId(node[settingVariationId])
+	evaluator.logger.Infof("Returning %v.", setting.Value)
+	return setting.Value, setting.VariationID, nil, nil
 }
 
 func (evaluator *rolloutEvaluator) logMatch(comparisonAttribute string, userValue interface{},
-	comparator float64, comparisonValue string, value interface{}) {
+	comparator Comparator, comparisonValue string, value interface{}) {
 	evaluator.logger.Infof("Evaluating rule: [%s:%s] [%s] [%s] => match, returning: %v",
-		comparisonAttribute, userValue, evaluator.comparatorTexts[int(comparator)], comparisonValue, value)
+		comparisonAttribute, userValue, comparator, comparisonValue, value)
 }
 
 func (evaluator *rolloutEvaluator) logNoMatch(comparisonAttribute string, userValue interface{},
-	comparator float64, comparisonValue string) {
+	comparator Comparator, comparisonValue string) {
 	evaluator.logger.Infof("Evaluating rule: [%s:%s] [%s] [%s] => no match",
-		comparisonAttribute, userValue, evaluator.comparatorTexts[int(comparator)], comparisonValue)
+		comparisonAttribute, userValue, comparator, comparisonValue)
 }
 
 func (evaluator *rolloutEvaluator) logFormatError(comparisonAttribute string, userValue interface{},
-	comparator float64, comparisonValue string, error string) {
+	comparator Comparator, comparisonValue string, error string) {
 	evaluator.logger.Infof("Evaluating rule: [%s:%s] [%s] [%s] => SKIP rule. Validation error: %s",
-		comparisonAttribute, userValue, evaluator.comparatorTexts[int(comparator)], comparisonValue, error)
-}
-
-func (evaluator *rolloutEvaluator) extractVariationId(variationId interface{}) string {
-	result, ok := variationId.(string)
-	if !ok {
-		return ""
-	}
-	return result
+		comparisonAttribute, userValue, comparator, comparisonValue, error)
 }
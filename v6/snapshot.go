@@ -0,0 +1,131 @@
+package configcat
+
+import (
+	"context"
+	"time"
+)
+
+// Snapshot is an immutable view of a single version of the configuration.
+// All the evaluations made on a Snapshot use the same configuration,
+// regardless of the refreshes happening in the meantime.
+type Snapshot struct {
+	client *Client
+	config *config
+}
+
+func newSnapshot(client *Client, conf *config) *Snapshot {
+	return &Snapshot{client: client, config: conf}
+}
+
+// GetValue returns the value of the setting identified by the given key.
+func (snapshot *Snapshot) GetValue(key string, defaultValue interface{}) interface{} {
+	return snapshot.GetValueForUser(key, defaultValue, nil)
+}
+
+// GetValueForUser returns the value of the setting identified by the given key.
+// Optional user argument can be passed to identify the caller.
+func (snapshot *Snapshot) GetValueForUser(key string, defaultValue interface{}, user *User) interface{} {
+	if len(key) == 0 {
+		panic("key cannot be empty")
+	}
+
+	return snapshot.client.parseJson(context.Background(), snapshot.config, key, defaultValue, user)
+}
+
+// GetValueDetails returns the value of the setting identified by the given key along with
+// the details of the evaluation, such as the matched targeting rule or percentage option.
+// Optional user argument can be passed to identify the caller.
+func (snapshot *Snapshot) GetValueDetails(key string, defaultValue interface{}, user *User) EvaluationDetails {
+	if len(key) == 0 {
+		panic("key cannot be empty")
+	}
+
+	return snapshot.client.getValueDetails(context.Background(), snapshot.config, key, defaultValue, user)
+}
+
+// GetBoolValueForUser returns the value of a boolean setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a boolean.
+// Optional user argument can be passed to identify the caller.
+func (snapshot *Snapshot) GetBoolValueForUser(key string, defaultValue bool, user *User) bool {
+	if value, ok := snapshot.getTypedValue("GetBoolValue", key, BoolSetting, defaultValue, user).(bool); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetIntValueForUser returns the value of a whole number setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a whole number.
+// Optional user argument can be passed to identify the caller.
+func (snapshot *Snapshot) GetIntValueForUser(key string, defaultValue int, user *User) int {
+	if value, ok := snapshot.getTypedValue("GetIntValue", key, IntSetting, defaultValue, user).(int); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetFloatValueForUser returns the value of a decimal number setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a decimal number.
+// Optional user argument can be passed to identify the caller.
+func (snapshot *Snapshot) GetFloatValueForUser(key string, defaultValue float64, user *User) float64 {
+	if value, ok := snapshot.getTypedValue("GetFloatValue", key, FloatSetting, defaultValue, user).(float64); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetStringValueForUser returns the value of a text setting identified by the given key.
+// The defaultValue is returned when the setting is missing or isn't a text.
+// Optional user argument can be passed to identify the caller.
+func (snapshot *Snapshot) GetStringValueForUser(key string, defaultValue string, user *User) string {
+	if value, ok := snapshot.getTypedValue("GetStringValue", key, StringSetting, defaultValue, user).(string); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetVariationId returns the Variation ID of the setting identified by the given key.
+func (snapshot *Snapshot) GetVariationId(key string, defaultVariationId string) string {
+	return snapshot.GetVariationIdForUser(key, defaultVariationId, nil)
+}
+
+// GetVariationIdForUser returns the Variation ID of the setting identified by the given key.
+// Optional user argument can be passed to identify the caller.
+func (snapshot *Snapshot) GetVariationIdForUser(key string, defaultVariationId string, user *User) string {
+	if len(key) == 0 {
+		panic("key cannot be empty")
+	}
+
+	return snapshot.client.parseVariationId(context.Background(), snapshot.config, key, defaultVariationId, user)
+}
+
+// GetAllKeys retrieves all the setting keys.
+// It fails when the snapshot holds no configuration.
+func (snapshot *Snapshot) GetAllKeys() ([]string, error) {
+	return snapshot.client.parser.getAllKeys(snapshot.config)
+}
+
+// FetchTime returns the time the configuration was fetched.
+// It's zero when the time is unknown.
+func (snapshot *Snapshot) FetchTime() time.Time {
+	if snapshot.config == nil {
+		return time.Time{}
+	}
+	return snapshot.config.fetchTime
+}
+
+// ETag returns the ETag of the HTTP response the configuration was fetched with.
+// It's empty when the ETag is unknown.
+func (snapshot *Snapshot) ETag() string {
+	if snapshot.config == nil {
+		return ""
+	}
+	return snapshot.config.eTag
+}
+
+func (snapshot *Snapshot) getTypedValue(method string, key string, kind SettingType, defaultValue interface{}, user *User) interface{} {
+	if len(key) == 0 {
+		panic("key cannot be empty")
+	}
+
+	return snapshot.client.parseTyped(context.Background(), snapshot.config, method, key, kind, defaultValue, user)
+}
@@ -0,0 +1,81 @@
+package configcat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClient_Snapshot(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: detailsJson, eTag: "\"first\""})
+	client := newInternal("fakeKey", ClientConfig{Mode: ManualPoll()}, fetcher)
+	defer client.Close()
+	client.Refresh()
+
+	snapshot := client.Snapshot()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: fmt.Sprintf(jsonFormat, "key", "\"changed\""), eTag: "\"second\""})
+	client.Refresh()
+
+	user := NewUserWithAdditionalAttributes("id", "a@example.com", "", nil)
+	if value := snapshot.GetStringValueForUser("key", "", user); value != "ruleValue" {
+		t.Errorf("Expecting the value of the snapshot configuration, got %q", value)
+	}
+	if id := snapshot.GetVariationIdForUser("key", "", user); id != "ruleId" {
+		t.Errorf("Expecting the Variation ID of the snapshot configuration, got %q", id)
+	}
+	if details := snapshot.GetValueDetails("key", "", user); details.MatchedRule == nil {
+		t.Error("Expecting the details of the matched targeting rule")
+	}
+	if eTag := snapshot.ETag(); eTag != "\"first\"" {
+		t.Errorf("Expecting the ETag of the snapshot configuration, got %q", eTag)
+	}
+	if fetchTime := snapshot.FetchTime(); time.Since(fetchTime) > time.Minute {
+		t.Errorf("Expecting the fetch time of the snapshot configuration, got %v", fetchTime)
+	}
+
+	latest := client.Snapshot()
+	if value := latest.GetValue("key", ""); value != "changed" {
+		t.Errorf("Expecting a new snapshot to hold the refreshed configuration, got %v", value)
+	}
+	if eTag := latest.ETag(); eTag != "\"second\"" {
+		t.Errorf("Expecting the ETag of the refreshed configuration, got %q", eTag)
+	}
+}
+
+func TestSnapshot_TypedValues(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Fetched, body: detailsJson})
+	client := newInternal("fakeKey", ClientConfig{Mode: ManualPoll(), Logger: DefaultLogger(LogLevelPanic)}, fetcher)
+	defer client.Close()
+	client.Refresh()
+
+	snapshot := client.Snapshot()
+	if value := snapshot.GetBoolValueForUser("key", true, nil); !value {
+		t.Error("Expecting the default value on a type mismatch")
+	}
+	if value := snapshot.GetIntValueForUser("missing", 42, nil); value != 42 {
+		t.Errorf("Expecting the default value of a missing setting, got %v", value)
+	}
+	if keys, err := snapshot.GetAllKeys(); err != nil || len(keys) != 1 || keys[0] != "key" {
+		t.Errorf("Expecting the keys of the snapshot configuration, got %v, %v", keys, err)
+	}
+}
+
+func TestSnapshot_NoConfig(t *testing.T) {
+	fetcher := newFakeConfigProvider()
+	fetcher.SetResponse(fetchResponse{status: Failure})
+	client := newInternal("fakeKey", ClientConfig{Mode: ManualPoll(), Logger: DefaultLogger(LogLevelPanic)}, fetcher)
+	defer client.Close()
+
+	snapshot := client.Snapshot()
+	if value := snapshot.GetFloatValueForUser("key", 1.5, nil); value != 1.5 {
+		t.Errorf("Expecting the default value without configuration, got %v", value)
+	}
+	if keys, err := snapshot.GetAllKeys(); keys != nil || err == nil {
+		t.Errorf("Expecting an error without configuration, got %v, %v", keys, err)
+	}
+	if !snapshot.FetchTime().IsZero() || snapshot.ETag() != "" {
+		t.Error("Expecting no fetch time and ETag without configuration")
+	}
+}
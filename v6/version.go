@@ -1,5 +1,5 @@
 package configcat
 
 const (
-	version = "6.0.0"
+	version = "6.1.0"
 )